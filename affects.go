@@ -0,0 +1,116 @@
+package soyusage
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/yext/soy/data"
+)
+
+// Affects determines whether a change from old to new input data could alter the output
+// of templates, according to the provided usage analysis. The paths of the observed values
+// that changed are returned, sorted.
+//
+// Changes are assessed in the same way as for CacheKey: values that are only checked for
+// existence are only considered changed if their truthiness changed, and values used for
+// their metadata only if whether they are null, the length of a list or keys of a map changed.
+// Values within lists are identified by the path of the list, and values accessed via
+// non-constant map keys are identified using the key that changed.
+func Affects(old, new data.Value, params Params) (bool, []Path) {
+	d := make(changedPaths)
+	oldMap, _ := old.(data.Map)
+	newMap, _ := new.(data.Map)
+	d.params(nil, oldMap, newMap, params)
+
+	var out []Path
+	for _, path := range d {
+		out = append(out, path)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return len(out) > 0, out
+}
+
+// changedPaths collects the paths of changed values, combining those found in list elements
+type changedPaths map[string]Path
+
+func (d changedPaths) add(path Path) {
+	d[path.String()] = path
+}
+
+// params compares the values of two maps for each of the provided params
+func (d changedPaths) params(path Path, old, new data.Map, params Params) {
+	var names = make(map[string]struct{})
+	for name := range params {
+		if _, isMapIndex := name.(MapIndex); !isMapIndex {
+			names[name.String()] = struct{}{}
+		}
+	}
+	if _, dynamic := params[MapIndex{}]; dynamic {
+		for _, in := range []data.Map{old, new} {
+			for key := range in {
+				names[key] = struct{}{}
+			}
+		}
+	}
+	for name := range names {
+		param, _ := paramForKey(params, name)
+		d.param(path.append(Name(name)), param, old[name], new[name])
+	}
+}
+
+// param compares the parts of two values observed through the usage of a param
+func (d changedPaths) param(path Path, param *Param, old, new data.Value) {
+	var (
+		hasMeta   = param.hasUsage(UsageMeta)
+		hasExists = param.hasUsage(UsageExists)
+	)
+	if param.usedEntirely() || !hasMeta && !hasExists && len(param.Children) == 0 {
+		if !reflect.DeepEqual(old, new) {
+			d.add(path)
+		}
+		return
+	}
+	if old == nil || new == nil {
+		if old != nil || new != nil {
+			d.add(path)
+		}
+		return
+	}
+
+	oldList, oldIsList := old.(data.List)
+	newList, newIsList := new.(data.List)
+	if oldIsList || newIsList {
+		if !oldIsList || !newIsList || len(oldList) != len(newList) {
+			d.add(path)
+			return
+		}
+		if hasMeta && !hasExists && len(param.Children) == 0 {
+			return
+		}
+		for i := range oldList {
+			d.param(path, param, oldList[i], newList[i])
+		}
+		return
+	}
+
+	oldMap, oldIsMap := old.(data.Map)
+	newMap, newIsMap := new.(data.Map)
+	if hasExists && old.Truthy() != new.Truthy() {
+		d.add(path)
+	}
+	if hasMeta && isNullish(old) != isNullish(new) {
+		d.add(path)
+	}
+	if hasMeta && (oldIsMap || newIsMap) && !reflect.DeepEqual(sortedKeys(oldMap), sortedKeys(newMap)) {
+		d.add(path)
+	}
+	if len(param.Children) > 0 {
+		if oldIsMap && newIsMap {
+			d.params(path, oldMap, newMap, param.Children)
+		} else if !reflect.DeepEqual(old, new) {
+			d.add(path)
+		}
+	}
+}
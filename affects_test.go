@@ -0,0 +1,142 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func TestAffects(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param loc
+	* @param day
+	* @param? photos
+	* @param? site
+	*/
+	{template .main}
+		{$loc.name}
+		{if $loc.closed}Closed{/if}
+		{length($photos)}
+		{foreach $phone in $loc.phones}
+			{$phone.number}
+		{/foreach}
+		{$loc.hours[$day].open}
+		{if isNonnull($site)}yes{else}no{/if}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old := map[string]interface{}{
+		"day": "mon",
+		"loc": map[string]interface{}{
+			"name":        "Store",
+			"closed":      true,
+			"description": "description",
+			"phones": []interface{}{
+				map[string]interface{}{"number": "1", "type": "main"},
+			},
+			"hours": map[string]interface{}{
+				"mon": map[string]interface{}{"open": "9", "close": "5"},
+			},
+		},
+		"photos": []interface{}{"a.jpg"},
+		"site":   "x",
+	}
+	var tests = []struct {
+		name     string
+		modify   func(in map[string]interface{})
+		expected []string
+	}{
+		{
+			name: "unused values",
+			modify: func(in map[string]interface{}) {
+				loc := in["loc"].(map[string]interface{})
+				loc["description"] = "changed"
+				loc["phones"] = []interface{}{
+					map[string]interface{}{"number": "1", "type": "mobile"},
+				}
+				loc["hours"] = map[string]interface{}{
+					"mon": map[string]interface{}{"open": "9", "close": "6"},
+				}
+				in["photos"] = []interface{}{"b.jpg"}
+				in["site"] = "y"
+				in["other"] = true
+			},
+		},
+		{
+			name: "used values",
+			modify: func(in map[string]interface{}) {
+				loc := in["loc"].(map[string]interface{})
+				loc["name"] = "Other store"
+				loc["closed"] = false
+				loc["phones"] = []interface{}{
+					map[string]interface{}{"number": "2"},
+				}
+				loc["hours"] = map[string]interface{}{
+					"mon": map[string]interface{}{"open": "10", "close": "5"},
+					"tue": map[string]interface{}{"open": "9"},
+				}
+				in["photos"] = []interface{}{}
+			},
+			expected: []string{
+				"loc.closed",
+				"loc.hours.mon.open",
+				"loc.hours.tue",
+				"loc.name",
+				"loc.phones.number",
+				"photos",
+			},
+		},
+		{
+			name: "same truthiness",
+			modify: func(in map[string]interface{}) {
+				in["loc"].(map[string]interface{})["closed"] = "yes"
+			},
+		},
+		{
+			name: "non-null check with different nullness",
+			modify: func(in map[string]interface{}) {
+				in["site"] = nil
+			},
+			expected: []string{
+				"site",
+			},
+		},
+		{
+			name: "removed values",
+			modify: func(in map[string]interface{}) {
+				delete(in, "photos")
+				in["loc"].(map[string]interface{})["name"] = nil
+			},
+			expected: []string{
+				"loc.name",
+				"photos",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			new := copyMap(old)
+			test.modify(new)
+			affected, paths := soyusage.Affects(data.New(old), data.New(new), params)
+			var got []string
+			for _, path := range paths {
+				got = append(got, path.String())
+			}
+			must.BeEqual(t, test.expected, got)
+			must.BeEqual(t, len(test.expected) > 0, affected)
+		})
+	}
+}
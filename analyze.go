@@ -39,6 +39,8 @@ func AnalyzeTemplate(templateName string, registry *template.Registry, options .
 		templateName: templateName,
 		parameters:   make(Params),
 		variables:    make(map[Identifier][]*Param),
+		constants:    make(map[Identifier][]interface{}),
+		headerParams: make(headerParams),
 		config: Config{
 			RecursionDepth: 2,
 		},
@@ -51,6 +53,7 @@ func AnalyzeTemplate(templateName string, registry *template.Registry, options .
 	for _, paramDoc := range template.Doc.Params {
 		s.parameters[Name(paramDoc.Name)] = newParam()
 	}
+	declareParams(s)
 
 	err := analyzeNode(s, usageUndefined, template.Node)
 	if err != nil {
@@ -63,6 +66,7 @@ func AnalyzeTemplate(templateName string, registry *template.Registry, options .
 		name := Name(paramDoc.Name)
 		if param, exists := s.parameters[name]; exists {
 			if !paramDoc.Optional || len(param.Children) > 0 || len(param.Usage) > 0 {
+				param.optional = paramDoc.Optional
 				filteredParams[name] = param
 			}
 		}
@@ -78,9 +82,13 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 		err := func() error {
 			switch v := node.(type) {
 			case *ast.AddNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeAdd(cs, v)
 			case *ast.AndNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				// The second argument is only evaluated if the first is true
+				if err := analyzeCondition(cs, v.Arg1); err != nil {
+					return err
+				}
+				return analyzeCondition(guarded(cs, v.Arg1, nil, false), v.Arg2)
 			case *ast.CallNode:
 				return analyzeCall(cs, v)
 			case *ast.CssNode:
@@ -90,23 +98,48 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 					return err
 				}
 			case *ast.DivNode:
-				return analyzeNode(cs, UsageFull, v.Children()...)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Children()...)
 			case *ast.ElvisNode:
-				return analyzeNode(cs, usageType, v.Arg1, v.Arg2)
+				// The second argument is only evaluated if the first is null
+				if err := analyzeNullable(cs, usageType, v.Arg1); err != nil {
+					return err
+				}
+				recordNullChecked(cs, v.Arg1)
+				return analyzeNode(conditionalScope(cs), usageType, v.Arg2)
 			case *ast.EqNode:
-				return analyzeNode(cs, UsageFull, v.Children()...)
+				return analyzeComparison(cs, v.Arg1, v.Arg2)
 			case *ast.ForNode:
 				variables, err := extractVariables(cs, v.List)
 				if err != nil {
 					return wrapError(s, node, err)
 				}
-				cs.variables[Name(v.Var)] = variables
+				if _, isDataRef := v.List.(*ast.DataRefNode); isDataRef {
+					for _, variable := range variables {
+						if !variable.isConstant() {
+							variable.iterated = true
+						}
+					}
+				}
 				constants, err := constantValues(cs, v.List)
 				if err != nil {
 					return wrapError(s, node, err)
 				}
-				cs.variables[Name(v.Var)] = appendConstants(cs.variables[Name(v.Var)], constants...)
-				return analyzeNode(cs, usageType, v.Body)
+				cs.bind(Name(v.Var), appendConstants(variables, constants...))
+				bodyScope := cs.inner()
+				bodyScope.loopVariable = Name(v.Var)
+				bodyScope.loops = append([]*Param(nil), cs.loops...)
+				for _, variable := range variables {
+					if variable.isConstant() {
+						continue
+					}
+					for _, loop := range cs.loops {
+						if loop == variable {
+							variable.nestedIteration = true
+						}
+					}
+					bodyScope.loops = append(bodyScope.loops, variable)
+				}
+				return analyzeNode(bodyScope, usageType, v.Body)
 			case *ast.FunctionNode:
 				var usage = UsageUnknown
 				switch v.Name {
@@ -119,24 +152,44 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 				case "round", "floor", "ceiling", "min", "max", "randomInt", "strContains":
 					usage = UsageFull
 				}
+				if v.Name == "isNonnull" {
+					if err := analyzeNode(cs, usage, v.Children()...); err != nil {
+						return err
+					}
+					for _, arg := range v.Args {
+						recordNullChecked(cs, arg)
+					}
+					return nil
+				}
+				if valueType, typed := functionTypes[v.Name]; typed {
+					return analyzeTyped(cs, valueType, usage, v.Children()...)
+				}
 				return analyzeNode(cs, usage, v.Children()...)
 			case *ast.GlobalNode:
 				// Globals assign primitive values and can be ignored for analyzing parameters
 			case *ast.GtNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg1, v.Arg2)
 			case *ast.GteNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg1, v.Arg2)
 			case *ast.IfNode:
+				// Each condition is only evaluated if all earlier conditions were false
+				var condScope = cs
 				for _, condition := range v.Conds {
 					condUsage := UsageFull
 					if _, isDataRef := condition.Cond.(*ast.DataRefNode); isDataRef {
 						condUsage = UsageExists
 					}
-					err := analyzeNode(cs, condUsage, condition.Cond)
+					err := analyzeTyped(condScope, TypeBool, condUsage, condition.Cond)
 					if err != nil {
 						return err
 					}
-					err = analyzeNode(cs, usageType, condition.Body)
+					recordNullChecked(condScope, condition.Cond)
+					bodyScope := condScope
+					if condition.Cond != nil {
+						bodyScope = guarded(condScope, condition.Cond, nil, false)
+						condScope = guarded(condScope, condition.Cond, nil, true)
+					}
+					err = analyzeNode(bodyScope, usageType, condition.Body)
 					if err != nil {
 						return err
 					}
@@ -147,13 +200,13 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 				if err != nil {
 					return wrapError(s, node, err)
 				}
-				cs.variables[Name(v.Name)] = variables
+				cs.bind(Name(v.Name), variables)
 			case *ast.LetValueNode:
 				variables, err := extractVariables(cs, v.Expr)
 				if err != nil {
 					return wrapError(s, node, err)
 				}
-				cs.variables[Name(v.Name)] = variables
+				cs.bind(Name(v.Name), variables)
 				return nil
 			case *ast.ListLiteralNode:
 				return analyzeNode(cs, usageType, v.Items...)
@@ -162,9 +215,9 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 			case *ast.LogNode:
 				return analyzeNode(cs, UsageFull, v.Body)
 			case *ast.LtNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg1, v.Arg2)
 			case *ast.LteNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg1, v.Arg2)
 			case *ast.MapLiteralNode:
 				for _, node := range v.Items {
 					if err := analyzeNode(cs, usageType, node); err != nil {
@@ -172,7 +225,7 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 					}
 				}
 			case *ast.ModNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg1, v.Arg2)
 			case *ast.MsgNode:
 				return analyzeNode(cs, usageType, v.Body)
 			case *ast.MsgPlaceholderNode:
@@ -180,7 +233,7 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 			case *ast.MsgPluralCaseNode:
 				return analyzeNode(cs, usageType, v.Body)
 			case *ast.MsgPluralNode:
-				if err := analyzeNode(cs, UsageFull, v.Value); err != nil {
+				if err := analyzeTyped(cs, TypeNumber, UsageFull, v.Value); err != nil {
 					return err
 				}
 				for _, c := range v.Cases {
@@ -189,40 +242,72 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 					}
 				}
 			case *ast.MulNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg1, v.Arg2)
 			case *ast.NegateNode:
-				return analyzeNode(cs, UsageFull, v.Arg)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg)
 			case *ast.NotEqNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeComparison(cs, v.Arg1, v.Arg2)
 			case *ast.NotNode:
-				return analyzeNode(cs, UsageFull, v.Arg)
+				return analyzeCondition(cs, v.Arg)
 			case *ast.PrintNode:
+				// Directives are recorded for params printed directly, so extraction
+				// can take account of directives such as truncate
+				if dataRef, isDataRef := v.Arg.(*ast.DataRefNode); isDataRef {
+					_, err := recordPrintedDataRef(cs, dataRef, v.Directives)
+					return err
+				}
 				err := analyzeNode(cs, UsageFull, v.Arg)
 				if err != nil {
 					return err
 				}
-				// Note, directives can be ignored as they are applied after
-				// the parameters are printed
 			case *ast.SwitchNode:
-				if err := analyzeNode(cs, UsageFull, v.Value); err != nil {
+				if err := analyzeNullable(cs, UsageFull, v.Value); err != nil {
+					return err
+				}
+				var caseValues []ast.Node
+				for _, c := range v.Cases {
+					caseValues = append(caseValues, c.Values...)
+				}
+				if err := recordCompared(cs, v.Value, caseValues...); err != nil {
 					return err
 				}
 				for _, c := range v.Cases {
 					if err := analyzeNode(cs, UsageFull, c.Values...); err != nil {
 						return err
 					}
-					if err := analyzeNode(cs, usageType, c.Body); err != nil {
+					caseScope, err := switchCaseScope(cs, v.Value, c.Values)
+					if err != nil {
+						return err
+					}
+					if len(c.Values) > 0 {
+						caseScope = guarded(caseScope, v.Value, c.Values, false)
+					} else if len(caseValues) > 0 {
+						caseScope = guarded(caseScope, v.Value, caseValues, true)
+					}
+					if err := analyzeNode(caseScope, usageType, c.Body); err != nil {
 						return err
 					}
 				}
 			case *ast.TemplateNode:
 				return analyzeNode(cs, usageType, v.Children()...)
 			case *ast.TernNode:
-				return analyzeNode(cs, usageType, v.Arg1, v.Arg2, v.Arg3)
+				if err := analyzeNullable(cs, usageType, v.Arg1); err != nil {
+					return err
+				}
+				recordNullChecked(cs, v.Arg1)
+				recordType(cs, TypeBool, v.Arg1)
+				if err := analyzeNode(guarded(cs, v.Arg1, nil, false), usageType, v.Arg2); err != nil {
+					return err
+				}
+				return analyzeNode(guarded(cs, v.Arg1, nil, true), usageType, v.Arg3)
 			case *ast.SubNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				return analyzeTyped(cs, TypeNumber, UsageFull, v.Arg1, v.Arg2)
 			case *ast.OrNode:
-				return analyzeNode(cs, UsageFull, v.Arg1, v.Arg2)
+				// The second argument is only evaluated if the first is false
+				if err := analyzeCondition(cs, v.Arg1); err != nil {
+					return err
+				}
+				return analyzeCondition(guarded(cs, v.Arg1, nil, true), v.Arg2)
 			case
 				*ast.StringNode,
 				*ast.RawTextNode,
@@ -248,6 +333,81 @@ func analyzeNode(s *scope, usageType UsageType, node ...ast.Node) error {
 	return nil
 }
 
+// analyzeComparison analyzes the arguments of an equality comparison,
+// recording any constant values that params are compared against.
+func analyzeComparison(s *scope, arg1, arg2 ast.Node) error {
+	// Values may be compared with null, so are not required
+	for _, arg := range []ast.Node{arg1, arg2} {
+		if err := analyzeNullable(s, UsageFull, arg); err != nil {
+			return err
+		}
+	}
+	if _, isNull := arg1.(*ast.NullNode); isNull {
+		recordNullChecked(s, arg2)
+	}
+	if _, isNull := arg2.(*ast.NullNode); isNull {
+		recordNullChecked(s, arg1)
+	}
+	if err := recordCompared(s, arg1, arg2); err != nil {
+		return err
+	}
+	return recordCompared(s, arg2, arg1)
+}
+
+// recordCompared records the constant values of the provided nodes as values that
+// a data ref has been compared against.
+// Values that are not fully constant are ignored.
+func recordCompared(s *scope, node ast.Node, values ...ast.Node) error {
+	dataRef, isDataRef := node.(*ast.DataRefNode)
+	if !isDataRef {
+		return nil
+	}
+	var constants []interface{}
+	for _, value := range values {
+		valueConstants, err := constantValues(s, value)
+		if err != nil {
+			return wrapError(s, value, err)
+		}
+		if !isFullyConstant(valueConstants) {
+			continue
+		}
+		constants = append(constants, valueConstants...)
+	}
+	if len(constants) == 0 {
+		return nil
+	}
+	for _, param := range resolveDataRef(s, dataRef) {
+		if !param.isConstant() {
+			param.addCompared(constants...)
+		}
+	}
+	return nil
+}
+
+// switchCaseScope returns the scope for the body of a switch case.
+// If the switch is on a variable and the case values are all constant, the
+// variable is known to have one of these values within the body.
+func switchCaseScope(s *scope, value ast.Node, caseValues []ast.Node) (*scope, error) {
+	dataRef, isDataRef := value.(*ast.DataRefNode)
+	if !isDataRef || len(dataRef.Access) > 0 || len(caseValues) == 0 {
+		return s, nil
+	}
+	var constants []interface{}
+	for _, caseValue := range caseValues {
+		values, err := constantValues(s, caseValue)
+		if err != nil {
+			return nil, wrapError(s, caseValue, err)
+		}
+		if !isFullyConstant(values) {
+			return s, nil
+		}
+		constants = append(constants, values...)
+	}
+	cs := s.inner()
+	cs.constants[Name(dataRef.Key)] = constants
+	return cs, nil
+}
+
 func findParams(
 	s *scope,
 	name Identifier,
@@ -270,6 +430,9 @@ func constantValues(s *scope, node ast.Node) ([]interface{}, error) {
 	case *ast.IntNode:
 		return []interface{}{int(v.Value)}, nil
 	case *ast.DataRefNode:
+		if constants, known := s.constants[Name(v.Key)]; known && len(v.Access) == 0 {
+			return constants, nil
+		}
 		params, err := findParams(s, Name(v.Key))
 		if err != nil {
 			return nil, wrapError(s, v, err)
@@ -292,17 +455,17 @@ func constantValues(s *scope, node ast.Node) ([]interface{}, error) {
 		if err != nil {
 			return nil, wrapError(s, v, err)
 		}
-		var out = make(map[string]struct{})
-		for _, arg1 := range arg1Values {
-			for _, arg2 := range arg2Values {
-				_, arg1IsString := arg1.(string)
-				_, arg2IsString := arg2.(string)
-				if arg1IsString || arg2IsString {
-					out[fmt.Sprint(arg1)+fmt.Sprint(arg2)] = struct{}{}
-				}
+		var out = make(map[interface{}]struct{})
+		for _, arg1 := range orNonConstant(arg1Values) {
+			for _, arg2 := range orNonConstant(arg2Values) {
+				out[addConstants(arg1, arg2)] = struct{}{}
 			}
 		}
-		return stringSetToInterface(out), nil
+		return setToInterface(out), nil
+	case *ast.TernNode:
+		return unionConstantValues(s, v.Arg2, v.Arg3)
+	case *ast.ElvisNode:
+		return unionConstantValues(s, v.Arg1, v.Arg2)
 	case *ast.FunctionNode:
 		if v.Name == "keys" {
 			return constantValues(s, v.Args[0])
@@ -363,7 +526,63 @@ func constantValues(s *scope, node ast.Node) ([]interface{}, error) {
 	return nil, nil
 }
 
-func intSetToInterface(set map[int]struct{}) []interface{} {
+// unionConstantValues returns the constant values of any of the provided nodes.
+// Nodes for which no constant values are known are treated as non-constant.
+func unionConstantValues(s *scope, nodes ...ast.Node) ([]interface{}, error) {
+	var out = make(map[interface{}]struct{})
+	for _, node := range nodes {
+		values, err := constantValues(s, node)
+		if err != nil {
+			return nil, wrapError(s, node, err)
+		}
+		for _, value := range orNonConstant(values) {
+			out[value] = struct{}{}
+		}
+	}
+	return setToInterface(out), nil
+}
+
+// addConstants returns the result of adding two constant values. Strings are
+// concatenated, while the result of adding a non-constant value is non-constant.
+func addConstants(arg1, arg2 interface{}) interface{} {
+	_, arg1IsNonConstant := arg1.(nonConstant)
+	_, arg2IsNonConstant := arg2.(nonConstant)
+	if arg1IsNonConstant || arg2IsNonConstant {
+		return nonConstant{}
+	}
+	int1, arg1IsInt := arg1.(int)
+	int2, arg2IsInt := arg2.(int)
+	if arg1IsInt && arg2IsInt {
+		return int1 + int2
+	}
+	_, arg1IsString := arg1.(string)
+	_, arg2IsString := arg2.(string)
+	if arg1IsString || arg2IsString {
+		return fmt.Sprint(arg1) + fmt.Sprint(arg2)
+	}
+	return nonConstant{}
+}
+
+// orNonConstant returns the provided constant values, or a single non-constant
+// value if there are none.
+func orNonConstant(values []interface{}) []interface{} {
+	if len(values) == 0 {
+		return []interface{}{nonConstant{}}
+	}
+	return values
+}
+
+// isFullyConstant returns true if all the provided values are known constants
+func isFullyConstant(values []interface{}) bool {
+	for _, value := range values {
+		if _, isNonConstant := value.(nonConstant); isNonConstant {
+			return false
+		}
+	}
+	return len(values) > 0
+}
+
+func setToInterface(set map[interface{}]struct{}) []interface{} {
 	var r []interface{}
 	for val := range set {
 		r = append(r, val)
@@ -371,7 +590,7 @@ func intSetToInterface(set map[int]struct{}) []interface{} {
 	return r
 }
 
-func stringSetToInterface(set map[string]struct{}) []interface{} {
+func intSetToInterface(set map[int]struct{}) []interface{} {
 	var r []interface{}
 	for val := range set {
 		r = append(r, val)
@@ -530,6 +749,15 @@ func extractVariables(
 			return nil, wrapError(s, node, err)
 		}
 		out = append(out, v2...)
+	case *ast.AddNode:
+		if err := analyzeNode(s, UsageFull, v.Arg1, v.Arg2); err != nil {
+			return nil, wrapError(s, node, err)
+		}
+		constants, err := constantValues(s, v)
+		if err != nil {
+			return nil, wrapError(s, v, err)
+		}
+		out = appendConstants(out, constants...)
 	case *ast.TernNode:
 		if err := analyzeNode(s, UsageReference, v.Arg1); err != nil {
 			return nil, wrapError(s, node, err)
@@ -31,14 +31,14 @@ func analyzeCall(
 				return wrapError(s, parameter, err)
 			}
 			n := Name(v.Key)
-			callScope.variables[n] = append(callScope.variables[n], constants...)
+			callScope.bind(n, append(callScope.variables[n], constants...))
 		case *ast.CallParamValueNode:
 			variables, err := extractVariables(s, v.Value)
 			if err != nil {
 				return wrapError(s, parameter, err)
 			}
 			n := Name(v.Key)
-			callScope.variables[n] = append(callScope.variables[n], variables...)
+			callScope.bind(n, append(callScope.variables[n], variables...))
 		}
 	}
 
@@ -49,7 +49,7 @@ func analyzeCall(
 				callScope.parameters[paramName] = paramValue
 			}
 			if variableValues, exists := s.variables[paramName]; exists {
-				callScope.variables[paramName] = variableValues
+				callScope.bind(paramName, variableValues)
 			}
 			_, paramPopulated := callScope.parameters[paramName]
 			_, variablePopulated := callScope.variables[paramName]
@@ -75,7 +75,7 @@ func analyzeCall(
 		}
 		for _, param := range variables {
 			for name, param := range param.Children {
-				callScope.variables[name] = append(callScope.variables[name], param)
+				callScope.bind(name, append(callScope.variables[name], param))
 			}
 			for _, templateParam := range template.Doc.Params {
 				paramName := Name(templateParam.Name)
@@ -97,6 +97,7 @@ func analyzeCall(
 		}
 	}
 
+	declareParams(callScope)
 	if err := analyzeNode(callScope, usageUndefined, template.Node); err != nil {
 		return wrapError(s, template.Node, err)
 	}
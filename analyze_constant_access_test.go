@@ -412,6 +412,189 @@ func TestAnalyzeConstantMapAccess(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "handles ternary and elvis keys",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param useHome
+				* @param field
+				*/
+				{template .main}
+					{$profile[$useHome ? 'c_homeAbout' : 'c_about']}
+					{let $fallback: $useHome ? 'c_homeName' : 'c_name' /}
+					{$profile[$fallback]}
+					{$profile[$field ?: 'c_other']}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]interface{}{
+				"useHome": "*",
+				"field":   "*",
+				"profile": map[string]interface{}{
+					"c_homeAbout": "*",
+					"c_about":     "*",
+					"c_homeName":  "*",
+					"c_name":      "*",
+					"c_other":     "*",
+					"[?]":         "*",
+				},
+			},
+		},
+		{
+			name: "handles concatenated keys",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param suffix
+				*/
+				{template .main}
+					{let $prefix: 'c_' /}
+					{let $field: $prefix + 'about' /}
+					{$profile[$field]}
+					{$profile[$prefix + 'name']}
+					{$profile[$prefix + $suffix].value}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]interface{}{
+				"suffix": "*",
+				"profile": map[string]interface{}{
+					"c_about": "*",
+					"c_name":  "*",
+					"[?]": map[string]interface{}{
+						"value": "*",
+					},
+				},
+			},
+		},
+		{
+			name: "handles keys restricted by switch cases",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param field
+				*/
+				{template .main}
+					{switch $field}
+						{case 'c_about', 'c_name'}
+							{$profile[$field]}
+						{case 'c_other'}
+							{$profile[$field].value}
+						{default}
+							{$profile[$field].name}
+					{/switch}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]interface{}{
+				"field": "*",
+				"profile": map[string]interface{}{
+					"c_about": "*",
+					"c_name":  "*",
+					"c_other": map[string]interface{}{
+						"value": "*",
+					},
+					"[?]": map[string]interface{}{
+						"name": "*",
+					},
+				},
+			},
+		},
+		{
+			name: "switch case values do not apply to a shadowing loop variable",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param field
+				* @param list
+				*/
+				{template .main}
+					{switch $field}
+						{case 'a'}
+							{foreach $field in $list}
+								{$profile[$field]}
+							{/foreach}
+					{/switch}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]interface{}{
+				"field": "*",
+				"list":  "*",
+				"profile": map[string]interface{}{
+					"[?]": "*",
+				},
+			},
+		},
+		{
+			name: "switch case values do not apply to a shadowing let",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param kind
+				* @param other
+				*/
+				{template .main}
+					{switch $kind}
+						{case 'a'}
+							{let $kind: $other /}
+							{$profile[$kind]}
+					{/switch}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]interface{}{
+				"kind":  "*",
+				"other": "*",
+				"profile": map[string]interface{}{
+					"[?]": "*",
+				},
+			},
+		},
+		{
+			name: "handles calculated list indices",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param list
+				* @param offset
+				*/
+				{template .main}
+					{foreach $i in range(2)}
+						{$list[$i + 1].name}
+					{/foreach}
+					{$list[$offset + 1].value}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]interface{}{
+				"offset": "*",
+				"list": map[string]interface{}{
+					"name": "*",
+					"[?]": map[string]interface{}{
+						"value": "*",
+					},
+				},
+			},
+		},
 	}
 	testAnalyze(t, tests)
 }
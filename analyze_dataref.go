@@ -7,9 +7,30 @@ func recordDataRef(
 	usageType UsageType,
 	node *ast.DataRefNode,
 ) ([]*Param, error) {
-	if usageType == usageUndefined {
+	return recordDataRefUsage(s, Usage{Type: usageType}, node)
+}
+
+// recordPrintedDataRef records a data ref that is printed directly, along with the
+// directives applied to it.
+func recordPrintedDataRef(
+	s *scope,
+	node *ast.DataRefNode,
+	directives []*ast.PrintDirectiveNode,
+) ([]*Param, error) {
+	return recordDataRefUsage(s, Usage{Type: UsageFull, directives: directives}, node)
+}
+
+func recordDataRefUsage(
+	s *scope,
+	usage Usage,
+	node *ast.DataRefNode,
+) ([]*Param, error) {
+	if usage.Type == usageUndefined {
 		return nil, newErrorf(s, node, "usage type was not set")
 	}
+	usage.Template = s.templateName
+	usage.node = node
+	usage.guards = s.guards
 
 	params, err := findParams(s, Name(node.Key))
 	if err != nil {
@@ -23,19 +44,25 @@ func recordDataRef(
 		if param.isConstant() {
 			continue
 		}
-		leaves, err := recordDataRefAccess(s, usageType, param, node.Access)
+		leaves, err := recordDataRefAccess(s, usage.Type, param, node.Access)
 		if err != nil {
 			return nil, wrapError(s, node, err)
 		}
+		out = append(out, leaves...)
+	}
 
-		for _, leaf := range leaves {
-			leaf.addUsageToLeaves(Usage{
-				Template: s.templateName,
-				Type:     usageType,
-				node:     node,
-			})
+	usage.chain = accessChain(s, node, params)
+	for i, access := range node.Access {
+		// A null-safe access indicates that the value accessed may be null
+		if isNullSafe(access) {
+			for _, param := range usage.chain[i] {
+				param.nullChecked = true
+			}
 		}
-		out = append(out, leaves...)
+	}
+	recordRequired(s, usage, node)
+	for _, leaf := range out {
+		leaf.addUsageToLeaves(usage)
 	}
 
 	return out, nil
@@ -61,7 +88,8 @@ func recordDataRefAccess(s *scope,
 		if err != nil {
 			return nil, wrapError(s, access, err)
 		}
-		names = append(names, constantValues...)
+		// Keys that cannot be determined are treated as non-constant
+		names = append(names, orNonConstant(constantValues)...)
 		err = analyzeNode(s, UsageFull, access.Arg)
 		if err != nil {
 			return nil, wrapError(s, access, err)
@@ -72,6 +100,8 @@ func recordDataRefAccess(s *scope,
 		var nextParam *Param
 		switch paramName := n.(type) {
 		case int:
+			// Elements of a list share the param of the list
+			param.types |= TypeList
 			nextParam = param
 		case nonConstant:
 			nextParam = param.getChildOrNew(MapIndex{})
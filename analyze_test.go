@@ -269,6 +269,42 @@ func TestAnalyzeParamHierarchy(t *testing.T) {
 	testAnalyze(t, tests)
 }
 
+func TestComparedValues(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param a
+	* @param kind
+	*/
+	{template .main}
+		{if $a.status == 'OPEN' or 'PENDING' == $a.status}Open{/if}
+		{if $a.status != 'CLOSED'}Not closed{/if}
+		{if $a.count == 3}Three{/if}
+		{if $a.other == $kind}Same{/if}
+		{let $closed: 'CLOSED' /}
+		{if $a.status == $closed}Closed{/if}
+		{switch $kind}
+			{case 'x', 'y'}
+			{case 2}
+			{default}
+		{/switch}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := params[soyusage.Name("a")]
+	must.BeEqual(t, []interface{}{"CLOSED", "OPEN", "PENDING"}, a.Children[soyusage.Name("status")].ComparedValues())
+	must.BeEqual(t, []interface{}{3}, a.Children[soyusage.Name("count")].ComparedValues())
+	must.BeEqual(t, []interface{}(nil), a.Children[soyusage.Name("other")].ComparedValues())
+	must.BeEqual(t, []interface{}{"x", "y", 2}, params[soyusage.Name("kind")].ComparedValues())
+}
+
 type analyzeTest struct {
 	name         string
 	templates    map[string]string
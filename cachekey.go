@@ -0,0 +1,199 @@
+package soyusage
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"math"
+	"sort"
+
+	"github.com/yext/soy/data"
+)
+
+// CacheKey returns a hash of the input data that only depends on the values that can be
+// observed by templates according to the provided usage analysis. Two inputs with the same
+// key will render the same output, so the key may be used for caching rendered templates.
+//
+// Map keys are hashed in sorted order. Values that are only checked for existence
+// contribute only their truthiness, while values used for their metadata contribute
+// only whether they are null, the length of lists and the keys of maps. Missing values
+// are distinguished from null values.
+func CacheKey(in data.Value, params Params) []byte {
+	w := keyWriter{sha256.New()}
+	inMap, isMap := in.(data.Map)
+	if !isMap {
+		w.value(in)
+	} else {
+		w.params(inMap, params)
+	}
+	return w.Sum(nil)
+}
+
+// keyWriter writes the projection of input data observed by templates to a hash
+type keyWriter struct {
+	hash.Hash
+}
+
+// params writes the values of a map for each of the provided params, in sorted order
+func (w keyWriter) params(in data.Map, params Params) {
+	var names []string
+	for name := range params {
+		if _, isMapIndex := name.(MapIndex); !isMapIndex {
+			names = append(names, name.String())
+		}
+	}
+	if _, dynamic := params[MapIndex{}]; dynamic {
+		for key := range in {
+			if _, isNamed := params[Name(key)]; !isNamed {
+				names = append(names, key)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	w.tag('p')
+	w.int(len(names))
+	for _, name := range names {
+		param, _ := paramForKey(params, name)
+		w.string(name)
+		w.param(param, in[name])
+	}
+}
+
+// param writes the parts of a value observed through the usage of a param
+func (w keyWriter) param(param *Param, in data.Value) {
+	if in == nil {
+		w.tag('a')
+		return
+	}
+	if param.usedEntirely() {
+		w.value(in)
+		return
+	}
+	if list, isList := in.(data.List); isList {
+		w.tag('l')
+		w.int(len(list))
+		if param.hasUsage(UsageMeta) && !param.hasUsage(UsageExists) && len(param.Children) == 0 {
+			// Only the length of the list is observed
+			return
+		}
+		for _, item := range list {
+			w.param(param, item)
+		}
+		return
+	}
+
+	var (
+		hasMeta   = param.hasUsage(UsageMeta)
+		hasExists = param.hasUsage(UsageExists)
+	)
+	if !hasMeta && !hasExists && len(param.Children) == 0 {
+		// Values with no known usage are assumed to be used entirely
+		w.value(in)
+		return
+	}
+	if hasExists {
+		w.tag('t')
+		w.bool(in.Truthy())
+	}
+	if hasMeta {
+		w.tag('k')
+		switch v := in.(type) {
+		case data.Null, data.Undefined:
+			w.tag('n')
+		case data.Map:
+			keys := sortedKeys(v)
+			w.tag('m')
+			w.int(len(keys))
+			for _, key := range keys {
+				w.string(key)
+			}
+		default:
+			w.tag('v')
+		}
+	}
+	if len(param.Children) > 0 {
+		if inMap, isMap := in.(data.Map); isMap {
+			w.params(inMap, param.Children)
+		} else {
+			w.value(in)
+		}
+	}
+}
+
+// value writes a complete value
+func (w keyWriter) value(in data.Value) {
+	switch v := in.(type) {
+	case nil:
+		w.tag('a')
+	case data.Undefined:
+		w.tag('u')
+	case data.Null:
+		w.tag('n')
+	case data.Bool:
+		w.tag('b')
+		w.bool(bool(v))
+	case data.Int:
+		w.tag('i')
+		w.int(int(v))
+	case data.Float:
+		w.tag('f')
+		w.uint(math.Float64bits(float64(v)))
+	case data.String:
+		w.tag('s')
+		w.string(string(v))
+	case data.List:
+		w.tag('l')
+		w.int(len(v))
+		for _, item := range v {
+			w.value(item)
+		}
+	case data.Map:
+		keys := sortedKeys(v)
+		w.tag('m')
+		w.int(len(keys))
+		for _, key := range keys {
+			w.string(key)
+			w.value(v[key])
+		}
+	default:
+		w.tag('x')
+		w.string(v.String())
+	}
+}
+
+func (w keyWriter) tag(tag byte) {
+	w.Write([]byte{tag})
+}
+
+func (w keyWriter) bool(b bool) {
+	if b {
+		w.tag(1)
+		return
+	}
+	w.tag(0)
+}
+
+func (w keyWriter) int(i int) {
+	w.uint(uint64(i))
+}
+
+func (w keyWriter) uint(i uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], i)
+	w.Write(buf[:])
+}
+
+func (w keyWriter) string(s string) {
+	w.int(len(s))
+	w.Write([]byte(s))
+}
+
+func sortedKeys(m data.Map) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
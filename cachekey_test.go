@@ -0,0 +1,173 @@
+package soyusage_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func TestCacheKey(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param loc
+	* @param? photos
+	* @param? hours
+	* @param? site
+	*/
+	{template .main}
+		{$loc.name}
+		{if $loc.closed}Closed{/if}
+		{length($photos)}
+		{length(keys($hours))}
+		{if isNonnull($site)}yes{else}no{/if}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := map[string]interface{}{
+		"loc": map[string]interface{}{
+			"name":        "Store",
+			"closed":      true,
+			"description": "description",
+		},
+		"photos": []interface{}{"a.jpg", "b.jpg"},
+		"hours": map[string]interface{}{
+			"mon": "9-5",
+			"tue": "9-5",
+		},
+		"site":  "x",
+		"other": 1,
+	}
+	var tests = []struct {
+		name    string
+		modify  func(in map[string]interface{})
+		changed bool
+	}{
+		{
+			name:    "unchanged",
+			modify:  func(in map[string]interface{}) {},
+			changed: false,
+		},
+		{
+			name: "unused field",
+			modify: func(in map[string]interface{}) {
+				in["loc"].(map[string]interface{})["description"] = "changed"
+				in["other"] = 2
+			},
+			changed: false,
+		},
+		{
+			name: "printed field",
+			modify: func(in map[string]interface{}) {
+				in["loc"].(map[string]interface{})["name"] = "Other store"
+			},
+			changed: true,
+		},
+		{
+			name: "existence check with same truthiness",
+			modify: func(in map[string]interface{}) {
+				in["loc"].(map[string]interface{})["closed"] = "yes"
+			},
+			changed: false,
+		},
+		{
+			name: "existence check with different truthiness",
+			modify: func(in map[string]interface{}) {
+				in["loc"].(map[string]interface{})["closed"] = false
+			},
+			changed: true,
+		},
+		{
+			name: "list elements with same length",
+			modify: func(in map[string]interface{}) {
+				in["photos"] = []interface{}{"c.jpg", "d.jpg"}
+			},
+			changed: false,
+		},
+		{
+			name: "list length",
+			modify: func(in map[string]interface{}) {
+				in["photos"] = []interface{}{"c.jpg"}
+			},
+			changed: true,
+		},
+		{
+			name: "map values with same keys",
+			modify: func(in map[string]interface{}) {
+				in["hours"] = map[string]interface{}{"mon": "closed", "tue": "closed"}
+			},
+			changed: false,
+		},
+		{
+			name: "map keys",
+			modify: func(in map[string]interface{}) {
+				in["hours"] = map[string]interface{}{"mon": "9-5", "wed": "9-5"}
+			},
+			changed: true,
+		},
+		{
+			name: "non-null check with same nullness",
+			modify: func(in map[string]interface{}) {
+				in["site"] = "y"
+			},
+			changed: false,
+		},
+		{
+			name: "non-null check with different nullness",
+			modify: func(in map[string]interface{}) {
+				in["site"] = nil
+			},
+			changed: true,
+		},
+		{
+			name: "missing and null values",
+			modify: func(in map[string]interface{}) {
+				in["photos"] = nil
+			},
+			changed: true,
+		},
+	}
+	baseKey := soyusage.CacheKey(data.New(base), params)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := copyMap(base)
+			test.modify(in)
+			key := soyusage.CacheKey(data.New(in), params)
+			if changed := !bytes.Equal(baseKey, key); changed != test.changed {
+				t.Errorf("expected changed=%v, got %v", test.changed, changed)
+			}
+		})
+	}
+
+	missing := copyMap(base)
+	delete(missing, "photos")
+	if bytes.Equal(soyusage.CacheKey(data.New(missing), params), soyusage.CacheKey(data.New(map[string]interface{}{
+		"loc":    base["loc"],
+		"hours":  base["hours"],
+		"photos": nil,
+	}), params)) {
+		t.Error("expected missing and null values to have different keys")
+	}
+}
+
+func copyMap(in map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	for key, value := range in {
+		if m, isMap := value.(map[string]interface{}); isMap {
+			value = copyMap(m)
+		}
+		out[key] = value
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/yext/soy/template"
+	"github.com/yext/soyusage"
+	"gopkg.in/yaml.v3"
+)
+
+// paramOutput describes a single param for JSON and YAML output
+type paramOutput struct {
+	Type     string                  `json:"type,omitempty" yaml:"type,omitempty"`
+	Usage    []usageOutput           `json:"usage,omitempty" yaml:"usage,omitempty"`
+	Compared []interface{}           `json:"compared,omitempty" yaml:"compared,omitempty"`
+	Children map[string]*paramOutput `json:"children,omitempty" yaml:"children,omitempty"`
+}
+
+// usageOutput describes a single usage for JSON and YAML output
+type usageOutput struct {
+	Type     string `json:"type" yaml:"type"`
+	Template string `json:"template" yaml:"template"`
+	File     string `json:"file" yaml:"file"`
+	Line     int    `json:"line" yaml:"line"`
+	Col      int    `json:"col" yaml:"col"`
+}
+
+// templateList collects template names from repeated or comma-separated flags
+type templateList []string
+
+func (t *templateList) String() string {
+	return strings.Join(*t, ",")
+}
+
+func (t *templateList) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			*t = append(*t, name)
+		}
+	}
+	return nil
+}
+
+func runAnalyze(args []string, stdout, stderr io.Writer) error {
+	var (
+		flags          = newFlagSet("analyze", stderr)
+		templates      templateList
+		format         = flags.String("format", "text", "output format: text, json or yaml")
+		recursionDepth = flags.Int("recursion", 2, "number of levels to which recursive calls will be analyzed")
+	)
+	flags.Var(&templates, "template", "fully qualified name of a template to analyze, may be repeated (defaults to all templates)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" && *format != "yaml" {
+		return fmt.Errorf("unknown format: %s", *format)
+	}
+
+	registry, err := compile(flags.Args())
+	if err != nil {
+		return err
+	}
+	if len(templates) == 0 {
+		for _, t := range registry.Templates {
+			templates = append(templates, t.Node.Name)
+		}
+		sort.Strings(templates)
+	}
+
+	var (
+		results = make(map[string]soyusage.Params)
+		failed  int
+	)
+	for _, templateName := range templates {
+		params, err := soyusage.AnalyzeTemplate(templateName, registry, soyusage.Recursion(*recursionDepth))
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", templateName, err)
+			failed++
+			continue
+		}
+		results[templateName] = params
+	}
+
+	switch *format {
+	case "json":
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(templatesOutput(registry, results))
+	case "yaml":
+		encoder := yaml.NewEncoder(stdout)
+		encoder.SetIndent(2)
+		err = encoder.Encode(templatesOutput(registry, results))
+	default:
+		err = writeText(stdout, registry, templates, results)
+	}
+	if err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("analysis failed for %d template(s)", failed)
+	}
+	return nil
+}
+
+func templatesOutput(registry *template.Registry, results map[string]soyusage.Params) map[string]map[string]*paramOutput {
+	var out = make(map[string]map[string]*paramOutput)
+	for templateName, params := range results {
+		out[templateName] = paramsOutput(registry, params)
+	}
+	return out
+}
+
+func paramsOutput(registry *template.Registry, params soyusage.Params) map[string]*paramOutput {
+	var out = make(map[string]*paramOutput)
+	for name, param := range params {
+		p := &paramOutput{
+			Usage:    usagesOutput(registry, param.Usage),
+			Compared: param.ComparedValues(),
+		}
+		if valueType := param.Type(); valueType != 0 {
+			p.Type = valueType.String()
+		}
+		if len(param.Children) > 0 {
+			p.Children = paramsOutput(registry, param.Children)
+		}
+		out[name.String()] = p
+	}
+	return out
+}
+
+func usagesOutput(registry *template.Registry, usages []soyusage.Usage) []usageOutput {
+	var out []usageOutput
+	for _, usage := range usages {
+		out = append(out, usageOutput{
+			Type:     usage.Type.String(),
+			Template: usage.Template,
+			File:     registry.Filename(usage.Template),
+			Line:     registry.LineNumber(usage.Template, usage.Node()),
+			Col:      registry.ColNumber(usage.Template, usage.Node()),
+		})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		if out[i].Line != out[j].Line {
+			return out[i].Line < out[j].Line
+		}
+		return out[i].Col < out[j].Col
+	})
+	return out
+}
+
+func writeText(w io.Writer, registry *template.Registry, templates []string, results map[string]soyusage.Params) error {
+	for _, templateName := range templates {
+		params, analyzed := results[templateName]
+		if !analyzed {
+			continue
+		}
+		if _, err := fmt.Fprintln(w, templateName); err != nil {
+			return err
+		}
+		if err := writeTextParams(w, 1, paramsOutput(registry, params)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeTextParams(w io.Writer, depth int, params map[string]*paramOutput) error {
+	var names []string
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	indent := strings.Repeat("  ", depth)
+	for _, name := range names {
+		param := params[name]
+		if _, err := fmt.Fprintf(w, "%s%s\n", indent, name); err != nil {
+			return err
+		}
+		for _, usage := range param.Usage {
+			if _, err := fmt.Fprintf(w, "%s  - %s at %s:%d:%d (%s)\n", indent, usage.Type, usage.File, usage.Line, usage.Col, usage.Template); err != nil {
+				return err
+			}
+		}
+		if err := writeTextParams(w, depth+1, param.Children); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func runExtract(args []string, stdout, stderr io.Writer) error {
+	var (
+		flags          = newFlagSet("extract", stderr)
+		templateName   = flags.String("template", "", "fully qualified name of the template to analyze (required)")
+		input          = flags.String("in", "-", "JSON file containing the template data, - for stdin")
+		top            = flags.Int("top", 10, "number of the largest removed subtrees to list in the summary")
+		quiet          = flags.Bool("q", false, "do not print the size summary")
+		recursionDepth = flags.Int("recursion", 2, "number of levels to which recursive calls will be analyzed")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *templateName == "" {
+		flags.Usage()
+		return fmt.Errorf("-template is required")
+	}
+
+	registry, err := compile(flags.Args())
+	if err != nil {
+		return err
+	}
+	params, err := soyusage.AnalyzeTemplate(*templateName, registry, soyusage.Recursion(*recursionDepth))
+	if err != nil {
+		return err
+	}
+
+	in, err := readJSON(*input)
+	if err != nil {
+		return err
+	}
+	report := soyusage.NewWasteReport()
+	out := report.Add(in, params)
+
+	outJSON, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(stdout, string(outJSON)); err != nil {
+		return err
+	}
+
+	if *quiet {
+		return nil
+	}
+	total := report.Total()
+	before := total.Used + total.Discarded
+	fmt.Fprintf(stderr, "bytes before: %d\n", before)
+	fmt.Fprintf(stderr, "bytes after:  %d (%.1f%% removed)\n", total.Used, removedPercent(before, total.Used))
+	if unused := report.Unused(*top); len(unused) > 0 {
+		fmt.Fprintln(stderr, "largest removed subtrees:")
+		for _, path := range unused {
+			fmt.Fprintf(stderr, "  %8d  %s\n", path.Discarded, path.Path)
+		}
+	}
+	return nil
+}
+
+func readJSON(filename string) (data.Value, error) {
+	var r io.Reader = os.Stdin
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("reading %s: %v", filename, err)
+	}
+	return jsonToData(value), nil
+}
+
+// jsonToData converts decoded JSON into soy data, preserving integers
+func jsonToData(value interface{}) data.Value {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return data.Int(i)
+		}
+		f, _ := v.Float64()
+		return data.Float(f)
+	case map[string]interface{}:
+		out := make(data.Map, len(v))
+		for key, item := range v {
+			out[key] = jsonToData(item)
+		}
+		return out
+	case []interface{}:
+		out := make(data.List, len(v))
+		for i, item := range v {
+			out[i] = jsonToData(item)
+		}
+		return out
+	}
+	return data.New(value)
+}
+
+func removedPercent(before, after int) float64 {
+	if before == 0 {
+		return 0
+	}
+	return 100 * float64(before-after) / float64(before)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yext/soyusage"
+)
+
+func runGoStruct(args []string, stdout, stderr io.Writer) error {
+	var (
+		flags          = newFlagSet("gostruct", stderr)
+		templateName   = flags.String("template", "", "fully qualified name of the template to analyze (required)")
+		packageName    = flags.String("package", "main", "package name for the generated code")
+		typeName       = flags.String("type", "Params", "name of the root struct type")
+		output         = flags.String("o", "", "file to write the generated code to, defaults to stdout")
+		recursionDepth = flags.Int("recursion", 2, "number of levels to which recursive calls will be analyzed")
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *templateName == "" {
+		flags.Usage()
+		return fmt.Errorf("-template is required")
+	}
+
+	registry, err := compile(flags.Args())
+	if err != nil {
+		return err
+	}
+	params, err := soyusage.AnalyzeTemplate(*templateName, registry, soyusage.Recursion(*recursionDepth))
+	if err != nil {
+		return err
+	}
+	source, err := soyusage.GenerateGoStructs(*packageName, *typeName, params)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		_, err = stdout.Write(source)
+		return err
+	}
+	return os.WriteFile(*output, source, 0644)
+}
@@ -0,0 +1,121 @@
+// Command soyusage analyzes soy templates to determine how their parameters are used.
+//
+// Usage:
+//
+//	soyusage <command> [flags] <soy files, directories or globs...>
+//
+// Run soyusage <command> -h for details of the flags accepted by each command.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/yext/soy"
+	"github.com/yext/soy/template"
+)
+
+// command defines a subcommand of the soyusage tool
+type command struct {
+	name        string
+	description string
+	run         func(args []string, stdout, stderr io.Writer) error
+}
+
+var commands = []command{
+	{
+		name:        "analyze",
+		description: "print the usage of each template's parameters",
+		run:         runAnalyze,
+	},
+	{
+		name:        "extract",
+		description: "prune JSON data to only the values used by a template",
+		run:         runExtract,
+	},
+	{
+		name:        "gostruct",
+		description: "generate Go structs for the data used by a template",
+		run:         runGoStruct,
+	},
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if err := c.run(args[1:], stdout, stderr); err != nil {
+			if err != flag.ErrHelp {
+				fmt.Fprintln(stderr, err)
+			}
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(stderr, "unknown command: %s\n", args[0])
+	printUsage(stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: soyusage <command> [flags] <soy files, directories or globs...>")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.description)
+	}
+}
+
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "usage: soyusage %s [flags] <soy files, directories or globs...>\n", name)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// compile loads all the soy files identified by the specified files,
+// directories and glob patterns into a registry.
+func compile(sources []string) (*template.Registry, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no soy files specified")
+	}
+	bundle := soy.NewBundle()
+	for _, source := range sources {
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", source, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files found matching %q", source)
+		}
+		sort.Strings(matches)
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				bundle = bundle.AddTemplateDir(match)
+				continue
+			}
+			bundle = bundle.AddTemplateFile(match)
+		}
+	}
+	return bundle.Compile()
+}
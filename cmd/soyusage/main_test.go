@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+)
+
+const testTemplate = `{namespace test}
+/**
+ * @param a
+ */
+{template .main}
+  {$a.b}
+  {if $a.c}
+    c
+  {/if}
+{/template}
+`
+
+func TestAnalyze(t *testing.T) {
+	dir := writeTestTemplates(t, map[string]string{"test.soy": testTemplate})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"analyze", dir}, &stdout, &stderr)
+	must.BeEqual(t, 0, code)
+	must.BeEqual(t, "", stderr.String())
+	file := filepath.Join(dir, "test.soy")
+	must.BeEqual(t, `test.main
+  a
+    b
+      - full at `+file+`:6:7 (test.main)
+    c
+      - exists at `+file+`:7:10 (test.main)
+`, stdout.String())
+}
+
+func TestAnalyzeCompileError(t *testing.T) {
+	dir := writeTestTemplates(t, map[string]string{"test.soy": "{namespace test}\n{template .main}\n{$undeclared}\n{/template}\n"})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"analyze", dir}, &stdout, &stderr)
+	must.BeEqual(t, 1, code)
+	if !strings.Contains(stderr.String(), "undeclared") {
+		t.Errorf("expected compile error in output, got: %s", stderr.String())
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	must.BeEqual(t, 2, run([]string{"unknown"}, &stdout, &stderr))
+}
+
+func writeTestTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestExtract(t *testing.T) {
+	dir := writeTestTemplates(t, map[string]string{
+		"test.soy":  testTemplate,
+		"data.json": `{"a": {"b": 1, "c": {"nested": true}, "d": "unused value"}, "e": [1, 2, 3]}`,
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"extract", "-template", "test.main", "-in", filepath.Join(dir, "data.json"), filepath.Join(dir, "*.soy")}, &stdout, &stderr)
+	must.BeEqual(t, 0, code)
+	must.BeEqual(t, `{
+  "a": {
+    "b": 1,
+    "c": ""
+  }
+}
+`, stdout.String())
+	must.BeEqual(t, `bytes before: 64
+bytes after:  20 (68.8% removed)
+largest removed subtrees:
+        14  a.d
+        13  a.c
+         7  e
+`, stderr.String())
+}
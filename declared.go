@@ -0,0 +1,234 @@
+package soyusage
+
+import (
+	"strings"
+
+	"github.com/yext/soy/ast"
+	"github.com/yext/soy/parse"
+)
+
+// Declaration describes the type declared for a template param in a header param,
+// such as {@param name: string}.
+type Declaration struct {
+	// Template provides the name of the template declaring the param.
+	Template string
+	// Type is the declared type, as written in the template.
+	Type string
+
+	node ast.Node
+}
+
+// Node provides a reference to the AST node for the header param declaring the type.
+func (d Declaration) Node() ast.Node {
+	return d.node
+}
+
+// Declarations lists the types declared for this param by the templates it was passed to.
+func (p *Param) Declarations() []Declaration {
+	return p.declarations
+}
+
+func (p *Param) declare(declaration Declaration) {
+	for _, existing := range p.declarations {
+		if existing.Template == declaration.Template && existing.node == declaration.node {
+			return
+		}
+	}
+	p.declarations = append(p.declarations, declaration)
+}
+
+// CheckDeclaredTypes validates that the types inferred from the usage of each param
+// are consistent with the types declared by the templates it was passed to, such as a
+// param declared as a string being iterated, or the fields of a number being accessed.
+// Params passed to a called template are checked against the declarations in that template.
+//
+// Declared types that cannot be interpreted, such as "?", "any" or protobuf message types,
+// are ignored. As any value may be used as a condition, this usage never conflicts with a
+// declared type.
+func CheckDeclaredTypes(params Params) []Diagnostic {
+	var out []Diagnostic
+	for name, param := range params {
+		out = append(out, checkDeclaredTypes(Path{name}, param)...)
+	}
+	sortDiagnostics(out)
+	return out
+}
+
+func checkDeclaredTypes(path Path, param *Param) []Diagnostic {
+	var out []Diagnostic
+	inferred := param.Type() &^ TypeBool
+	for _, declaration := range param.declarations {
+		declared, known := parseDeclaredType(declaration.Type)
+		if !known {
+			continue
+		}
+		if conflict := inferred &^ declared.allowed(); conflict != 0 {
+			diagnostic := newDiagnostic(
+				path,
+				param,
+				"field is used as %v, but is declared as %q in %s",
+				conflict,
+				declaration.Type,
+				declaration.Template,
+			)
+			diagnostic.Declarations = []Declaration{declaration}
+			out = append(out, diagnostic)
+		}
+	}
+	for name, child := range param.Children {
+		out = append(out, checkDeclaredTypes(path.append(name), child)...)
+	}
+	return out
+}
+
+// declaredType describes the types permitted by a declaration
+type declaredType struct {
+	types ValueType
+	// elements holds the types of the elements of lists
+	elements ValueType
+	// anyElements is set if the type of the elements of lists is not known
+	anyElements bool
+}
+
+// allowed returns the inferred types that are consistent with this declaration.
+// Values within lists share the param of the list, so the types of elements are included.
+func (d declaredType) allowed() ValueType {
+	var allowed = d.types
+	if d.types.Has(TypeList) {
+		if d.anyElements {
+			return TypeString | TypeNumber | TypeBool | TypeList | TypeMap
+		}
+		allowed |= d.elements
+	}
+	return allowed
+}
+
+// declaredTypeNames maps the names of primitive soy types to the types of their values
+var declaredTypeNames = map[string]ValueType{
+	"string":               TypeString,
+	"html":                 TypeString,
+	"uri":                  TypeString,
+	"js":                   TypeString,
+	"css":                  TypeString,
+	"attributes":           TypeString,
+	"trusted_resource_uri": TypeString,
+	"int":                  TypeNumber,
+	"float":                TypeNumber,
+	"number":               TypeNumber,
+	"bool":                 TypeBool,
+}
+
+// parseDeclaredType interprets the type expression from a header param.
+// False is returned if the type permits values of any type, or is not recognized.
+func parseDeclaredType(expr string) (declaredType, bool) {
+	var out declaredType
+	for _, part := range splitTopLevel(strings.TrimSpace(expr), '|') {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "null" || part == "undefined":
+		case strings.HasPrefix(part, "list<") && strings.HasSuffix(part, ">"):
+			out.types |= TypeList
+			elements, known := parseDeclaredType(part[len("list<") : len(part)-1])
+			if !known {
+				out.anyElements = true
+			}
+			out.elements |= elements.allowed()
+		case strings.HasPrefix(part, "map<"), strings.HasPrefix(part, "legacy_object_map<"), strings.HasPrefix(part, "["):
+			out.types |= TypeMap
+		default:
+			valueType, known := declaredTypeNames[part]
+			if !known {
+				return declaredType{}, false
+			}
+			out.types |= valueType
+		}
+	}
+	return out, out.types != 0
+}
+
+// splitTopLevel splits a type expression on a separator, ignoring separators
+// within angle or square brackets.
+func splitTopLevel(expr string, sep rune) []string {
+	var (
+		out   []string
+		depth int
+		start int
+	)
+	for i, r := range expr {
+		switch r {
+		case '<', '[':
+			depth++
+		case '>', ']':
+			depth--
+		case sep:
+			if depth == 0 {
+				out = append(out, expr[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(out, expr[start:])
+}
+
+// headerParams caches the header params declared by each template, which are not
+// retained by the template registry.
+type headerParams map[string][]*ast.HeaderParamNode
+
+// declareParams records the declared types of the params of the template for a scope.
+func declareParams(s *scope) {
+	for _, headerParam := range s.headerParams.lookup(s, s.templateName) {
+		if headerParam.Type.Expr == "" {
+			continue
+		}
+		declaration := Declaration{
+			Template: s.templateName,
+			Type:     headerParam.Type.Expr,
+			node:     headerParam,
+		}
+		name := Name(headerParam.Name)
+		for _, param := range s.variables[name] {
+			if !param.isConstant() {
+				param.declare(declaration)
+			}
+		}
+		if param, exists := s.parameters[name]; exists {
+			param.declare(declaration)
+		}
+	}
+}
+
+// lookup returns the header params for a template, parsing the file containing the
+// template if it has not already been parsed.
+func (h headerParams) lookup(s *scope, templateName string) []*ast.HeaderParamNode {
+	if params, parsed := h[templateName]; parsed {
+		return params
+	}
+	h[templateName] = nil
+	filename := s.registry.Filename(templateName)
+	for _, file := range s.registry.SoyFiles {
+		if file.Name != filename || !strings.Contains(file.Text, "{@param") {
+			continue
+		}
+		// The file was parsed successfully when the registry was compiled
+		parsed, err := parse.SoyFile(file.Name, file.Text)
+		if err != nil {
+			continue
+		}
+		for _, node := range parsed.Body {
+			template, isTemplate := node.(*ast.TemplateNode)
+			if !isTemplate {
+				continue
+			}
+			var params []*ast.HeaderParamNode
+			for _, node := range template.Body.Nodes {
+				param, isParam := node.(*ast.HeaderParamNode)
+				if !isParam {
+					break
+				}
+				params = append(params, param)
+			}
+			h[template.Name] = params
+		}
+	}
+	return h[templateName]
+}
@@ -0,0 +1,144 @@
+package soyusage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestCheckDeclaredTypes(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		expected     []string
+	}{
+		{
+			name: "usage consistent with declarations",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+
+				{template .main}
+					{@param loc: [name: string, phones: list<[number: string]>]}
+					{@param count: int}
+					{@param? tags: list<string>|null}
+					{@param other: ?}
+					{$loc.name}
+					{foreach $phone in $loc.phones}
+						{$phone.number}
+					{/foreach}
+					{if $count > 1}Many{/if}
+					{foreach $tag in $tags}
+						{if $tag == 'new'}New{/if}
+					{/foreach}
+					{$other.field}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+		},
+		{
+			name: "usage conflicts with declarations",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+
+				{template .main}
+					{@param name: string}
+					{@param count: number}
+					{@param enabled: bool}
+					{foreach $part in $name}
+						{$part}
+					{/foreach}
+					{$count.value}
+					{if $enabled}Enabled{/if}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				`count: field is used as map, but is declared as "number" in test.main`,
+				`name: field is used as list, but is declared as "string" in test.main`,
+			},
+		},
+		{
+			name: "checks params passed to called templates",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.hours.monday}
+					{call .hours}
+						{param hours: $loc.hours /}
+					{/call}
+				{/template}
+
+				{template .hours}
+					{@param hours: list<string>}
+					{length($hours)}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				`loc.hours: field is used as map, but is declared as "list<string>" in test.hours`,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, diagnostic := range soyusage.CheckDeclaredTypes(params) {
+				got = append(got, diagnostic.String())
+				must.BeEqual(t, 1, len(diagnostic.Declarations))
+			}
+			must.BeEqual(t, test.expected, got)
+		})
+	}
+}
+
+func TestCheckDeclaredTypesFormat(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `{namespace test}
+
+{template .main}
+	{@param count: int}
+	{$count.value}
+{/template}
+`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := soyusage.CheckDeclaredTypes(params)
+	must.BeEqual(t, 1, len(diagnostics))
+	formatted := diagnostics[0].Format(registry)
+	if !strings.Contains(formatted, "test.soy, line 5, col 10 (test.main)") {
+		t.Errorf("expected usage position, got:\n%s", formatted)
+	}
+	if !strings.Contains(formatted, "test.soy, line 4, col 10 (test.main, declared as int)") {
+		t.Errorf("expected declaration position, got:\n%s", formatted)
+	}
+}
@@ -0,0 +1,136 @@
+package soyusage
+
+import (
+	"fmt"
+	"os"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// dynamicMessages lists well-known message types whose fields are not known
+// in advance and so cannot be checked.
+var dynamicMessages = map[protoreflect.FullName]struct{}{
+	"google.protobuf.Struct":    {},
+	"google.protobuf.Value":     {},
+	"google.protobuf.ListValue": {},
+	"google.protobuf.Any":       {},
+}
+
+// LoadDescriptorSet reads a compiled FileDescriptorSet, as produced by
+// protoc --descriptor_set_out, from the specified file.
+func LoadDescriptorSet(filename string) (*protoregistry.Files, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var set descriptorpb.FileDescriptorSet
+	if err := proto.Unmarshal(content, &set); err != nil {
+		return nil, fmt.Errorf("reading descriptor set %s: %v", filename, err)
+	}
+	return protodesc.NewFiles(&set)
+}
+
+// CheckAgainstDescriptors validates that every field accessed within params exists
+// in the corresponding protobuf message.
+// The bindings map the names of template params to the full name of their message type.
+// Params without a binding are not checked.
+//
+// Fields may be referenced by either their proto name or their JSON name.
+// Fields accessed via a non-constant map index, and fields of well-known dynamic
+// types such as google.protobuf.Struct, cannot be validated and are ignored.
+func CheckAgainstDescriptors(
+	params Params,
+	files *protoregistry.Files,
+	bindings map[string]protoreflect.FullName,
+) ([]Diagnostic, error) {
+	var diagnostics []Diagnostic
+	for name, messageName := range bindings {
+		param, used := params[Name(name)]
+		if !used {
+			continue
+		}
+		descriptor, err := files.FindDescriptorByName(messageName)
+		if err != nil {
+			return nil, fmt.Errorf("message not found for param %q: %s", name, messageName)
+		}
+		message, isMessage := descriptor.(protoreflect.MessageDescriptor)
+		if !isMessage {
+			return nil, fmt.Errorf("%s is not a message type", messageName)
+		}
+		diagnostics = append(diagnostics, checkMessage(Path{Name(name)}, param, message)...)
+	}
+	sortDiagnostics(diagnostics)
+	return diagnostics, nil
+}
+
+func checkMessage(path Path, param *Param, message protoreflect.MessageDescriptor) []Diagnostic {
+	if _, isDynamic := dynamicMessages[message.FullName()]; isDynamic {
+		return nil
+	}
+	var out []Diagnostic
+	for name, child := range param.Children {
+		if _, isMapIndex := name.(MapIndex); isMapIndex {
+			continue
+		}
+		childPath := path.append(name)
+		field := findField(message, name.String())
+		if field == nil {
+			out = append(out, newDiagnostic(
+				childPath,
+				child,
+				"field %q does not exist in %s%s",
+				name,
+				message.FullName(),
+				didYouMean(name.String(), fieldNames(message)),
+			))
+			continue
+		}
+		if field.IsMap() {
+			for key, value := range child.Children {
+				out = append(out, checkFieldValue(childPath.append(key), value, field.MapValue())...)
+			}
+			continue
+		}
+		out = append(out, checkFieldValue(childPath, child, field)...)
+	}
+	return out
+}
+
+func checkFieldValue(path Path, param *Param, field protoreflect.FieldDescriptor) []Diagnostic {
+	if len(param.Children) == 0 {
+		return nil
+	}
+	if field.Message() == nil {
+		return []Diagnostic{
+			newDiagnostic(path, param, "field %q is of type %v and has no fields", field.Name(), field.Kind()),
+		}
+	}
+	return checkMessage(path, param, field.Message())
+}
+
+func findField(message protoreflect.MessageDescriptor, name string) protoreflect.FieldDescriptor {
+	fields := message.Fields()
+	if field := fields.ByName(protoreflect.Name(name)); field != nil {
+		return field
+	}
+	return fields.ByJSONName(name)
+}
+
+func fieldNames(message protoreflect.MessageDescriptor) []string {
+	var (
+		fields = message.Fields()
+		out    []string
+	)
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		out = append(out, string(field.Name()))
+		if field.JSONName() != string(field.Name()) {
+			out = append(out, field.JSONName())
+		}
+	}
+	return out
+}
@@ -0,0 +1,156 @@
+package soyusage_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestCheckAgainstDescriptors(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param loc
+	* @param other
+	*/
+	{template .main}
+		{$loc.adress}
+		{$loc.address.city}
+		{$loc.address.zip.code}
+		{$loc.display_name}
+		{$loc.displayName}
+		{$loc.hours['mon'].open}
+		{$loc.hours['tue'].close}
+		{$loc.attributes.anything.goes}
+		{$other.unchecked}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "descriptors.pb")
+	content, err := proto.Marshal(testDescriptorSet())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filename, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+	files, err := soyusage.LoadDescriptorSet(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics, err := soyusage.CheckAgainstDescriptors(params, files, map[string]protoreflect.FullName{
+		"loc": "test.Location",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diagnostic := range diagnostics {
+		got = append(got, diagnostic.String())
+	}
+	must.BeEqual(t, []string{
+		`loc.address.zip: field "zip" is of type string and has no fields`,
+		`loc.adress: field "adress" does not exist in test.Location, did you mean "address"?`,
+		`loc.hours.tue.close: field "close" does not exist in test.Hours`,
+	}, got)
+	if formatted := diagnostics[1].Format(registry); !strings.Contains(formatted, "test.soy, line 8") {
+		t.Errorf("expected position in formatted diagnostic, got: %s", formatted)
+	}
+
+	_, err = soyusage.CheckAgainstDescriptors(params, files, map[string]protoreflect.FullName{
+		"loc": "test.Missing",
+	})
+	if err == nil {
+		t.Error("expected error for missing message type")
+	}
+}
+
+func testDescriptorSet() *descriptorpb.FileDescriptorSet {
+	var (
+		optional = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		repeated = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		str      = descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum()
+		message  = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	)
+	field := func(name string, number int32, label *descriptorpb.FieldDescriptorProto_Label, typ *descriptorpb.FieldDescriptorProto_Type, typeName string) *descriptorpb.FieldDescriptorProto {
+		f := &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Label:  label,
+			Type:   typ,
+		}
+		if typeName != "" {
+			f.TypeName = proto.String(typeName)
+		}
+		return f
+	}
+	return &descriptorpb.FileDescriptorSet{
+		File: []*descriptorpb.FileDescriptorProto{
+			{
+				Name:    proto.String("google/protobuf/struct.proto"),
+				Package: proto.String("google.protobuf"),
+				Syntax:  proto.String("proto3"),
+				MessageType: []*descriptorpb.DescriptorProto{
+					{Name: proto.String("Struct")},
+				},
+			},
+			{
+				Name:       proto.String("test.proto"),
+				Package:    proto.String("test"),
+				Syntax:     proto.String("proto3"),
+				Dependency: []string{"google/protobuf/struct.proto"},
+				MessageType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("Location"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("address", 1, optional, message, ".test.Address"),
+							field("display_name", 2, optional, str, ""),
+							field("hours", 3, repeated, message, ".test.Location.HoursEntry"),
+							field("attributes", 4, optional, message, ".google.protobuf.Struct"),
+						},
+						NestedType: []*descriptorpb.DescriptorProto{
+							{
+								Name: proto.String("HoursEntry"),
+								Field: []*descriptorpb.FieldDescriptorProto{
+									field("key", 1, optional, str, ""),
+									field("value", 2, optional, message, ".test.Hours"),
+								},
+								Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+							},
+						},
+					},
+					{
+						Name: proto.String("Address"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("city", 1, optional, str, ""),
+							field("zip", 2, optional, str, ""),
+						},
+					},
+					{
+						Name: proto.String("Hours"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("open", 1, optional, str, ""),
+						},
+					},
+				},
+			},
+		},
+	}
+}
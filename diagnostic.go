@@ -0,0 +1,123 @@
+package soyusage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yext/soy/template"
+)
+
+// Diagnostic describes a problem found when checking parameter usage
+// against an external definition of the data.
+type Diagnostic struct {
+	// Path identifies the parameter or field with the problem
+	Path Path
+	// Message describes the problem
+	Message string
+	// Usage lists the usages of the parameter that triggered this diagnostic
+	Usage []Usage
+	// Declarations lists the declarations of the parameter's type that
+	// triggered this diagnostic, if any
+	Declarations []Declaration
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%v: %v", d.Path, d.Message)
+}
+
+// Format describes the diagnostic along with the file, line and column of each usage.
+func (d Diagnostic) Format(registry *template.Registry) string {
+	var out = []string{d.String()}
+	for _, usage := range d.Usage {
+		out = append(out, fmt.Sprintf(
+			"\t%s, line %d, col %d (%s)",
+			registry.Filename(usage.Template),
+			registry.LineNumber(usage.Template, usage.node),
+			registry.ColNumber(usage.Template, usage.node),
+			usage.Template,
+		))
+	}
+	for _, declaration := range d.Declarations {
+		out = append(out, fmt.Sprintf(
+			"\t%s, line %d, col %d (%s, declared as %s)",
+			registry.Filename(declaration.Template),
+			registry.LineNumber(declaration.Template, declaration.node),
+			registry.ColNumber(declaration.Template, declaration.node),
+			declaration.Template,
+			declaration.Type,
+		))
+	}
+	return strings.Join(out, "\n")
+}
+
+func newDiagnostic(path Path, param *Param, message string, args ...interface{}) Diagnostic {
+	return Diagnostic{
+		Path:    path,
+		Message: fmt.Sprintf(message, args...),
+		Usage:   param.leafUsage(),
+	}
+}
+
+func sortDiagnostics(diagnostics []Diagnostic) {
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		return diagnostics[i].Path.String() < diagnostics[j].Path.String()
+	})
+}
+
+// suggest returns the candidate closest to name, or an empty string if
+// no candidate is close enough to be a likely typo.
+func suggest(name string, candidates []string) string {
+	var (
+		best         string
+		bestDistance = len(name)/3 + 1
+	)
+	for _, candidate := range candidates {
+		distance := editDistance(strings.ToLower(name), strings.ToLower(candidate))
+		if distance < bestDistance || (distance == bestDistance && best != "" && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+func didYouMean(name string, candidates []string) string {
+	if suggestion := suggest(name, candidates); suggestion != "" {
+		return fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+	return ""
+}
+
+// editDistance computes the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	var (
+		ar, br = []rune(a), []rune(b)
+		prev   = make([]int, len(br)+1)
+		cur    = make([]int, len(br)+1)
+	)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(br)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
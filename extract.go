@@ -1,12 +1,60 @@
 package soyusage
 
 import (
+	"unicode/utf8"
+
+	"github.com/yext/soy/ast"
 	"github.com/yext/soy/data"
 )
 
+// prefixPreservingDirectives lists the builtin print directives that, when applied to
+// a prefix of a string, produce a prefix of the result for the whole string at least as
+// long as the input prefix. These directives may be applied before truncate without
+// preventing extraction from truncating strings.
+var prefixPreservingDirectives = map[string]struct{}{
+	"id":                {},
+	"noAutoescape":      {},
+	"escapeHtml":        {},
+	"escapeUri":         {},
+	"escapeJsString":    {},
+	"changeNewlineToBr": {},
+	"insertWordBreaks":  {},
+	"json":              {},
+}
+
 // Extract returns a version of the input data containing only
 // the values specified in the provided usage analysis.
+// Map keys that are accessed with a known set of constant values are retained
+// individually, all keys are only retained for maps accessed with non-constant keys.
+// Strings that are only ever printed using the truncate directive are shortened
+// to the longest length at which they will still be truncated in the same way.
+// Values only used for their metadata, such as with length(), keys() or isNonnull(),
+// retain only whether they are null, the length of lists and the keys of maps.
 func Extract(in data.Value, params Params) data.Value {
+	return extract(in, params, emptyString)
+}
+
+// existencePlaceholder returns the value retained in place of a value that is only
+// checked for existence
+type existencePlaceholder func(in data.Value) data.Value
+
+func emptyString(data.Value) data.Value {
+	return data.String("")
+}
+
+// sameTruthiness returns an empty value that soy will consider to have the same
+// truthiness as the input, so templates render in the same way.
+func sameTruthiness(in data.Value) data.Value {
+	switch in.(type) {
+	case data.Map:
+		return data.Map{}
+	case data.List:
+		return data.List{}
+	}
+	return data.Bool(in.Truthy())
+}
+
+func extract(in data.Value, params Params, placeholder existencePlaceholder) data.Value {
 	var (
 		out          = make(data.Map)
 		inMap, isMap = in.(data.Map)
@@ -15,17 +63,20 @@ func Extract(in data.Value, params Params) data.Value {
 		return in
 	}
 
-	for paramName, param := range params {
-		inValue := inMap[paramName.String()]
-		if (MapIndex{}) == paramName {
-			values := extractMap(param, inMap)
-			for name, value := range values {
-				out[name] = value
+	if _, dynamic := params[MapIndex{}]; dynamic {
+		for key, value := range inMap {
+			if param, exists := paramForKey(params, key); exists {
+				if outVal := extractParam(param, value, placeholder); outVal != nil {
+					out[key] = outVal
+				}
 			}
 		}
+		return out
+	}
 
-		var outVal data.Value
-		outVal = extractParam(param, inValue)
+	for paramName, param := range params {
+		inValue := inMap[paramName.String()]
+		outVal := extractParam(param, inValue, placeholder)
 		if outVal != nil {
 			out[paramName.String()] = outVal
 		}
@@ -33,48 +84,137 @@ func Extract(in data.Value, params Params) data.Value {
 	return out
 }
 
-func extractMap(param *Param, in data.Value) data.Map {
-	var (
-		out          = make(data.Map)
-		inMap, isMap = in.(data.Map)
-	)
-	if !isMap {
-		return nil
+// paramForKey returns the param describing the value of a key in a map.
+// Keys accessed both by name and via a map index combine the usage of both.
+func paramForKey(params Params, key string) (*Param, bool) {
+	named, isNamed := params[Name(key)]
+	index, isIndexed := params[MapIndex{}]
+	switch {
+	case isNamed && isIndexed:
+		combined := newParam()
+		combined.merge(named)
+		combined.merge(index)
+		return combined, true
+	case isNamed:
+		return named, true
 	}
-	for name, value := range inMap {
-		out[name] = Extract(value, param.Children)
-	}
-	return out
+	return index, isIndexed
 }
 
-func extractParam(param *Param, in data.Value) data.Value {
+func extractParam(param *Param, in data.Value, placeholder existencePlaceholder) data.Value {
 	if in == nil {
 		return nil
 	}
 	if listValue, isList := in.(data.List); isList {
 		var outList data.List
 		for _, value := range listValue {
-			outList = append(outList, extractParam(param, value))
+			outList = append(outList, extractParam(param, value, placeholder))
 		}
 		return outList
 	}
 	var (
 		isFull   bool
 		isExists bool
+		isMeta   bool
 	)
 	for _, usage := range param.Usage {
 		switch usage.Type {
-		case UsageFull, UsageUnknown, UsageMeta:
+		case UsageFull, UsageUnknown:
 			isFull = true
+		case UsageMeta:
+			isMeta = true
 		case UsageExists:
 			isExists = true
 		}
 	}
 	if isFull {
+		if maxLen, truncatable := param.truncatedLength(); truncatable {
+			if str, isString := in.(data.String); isString {
+				return data.String(truncateString(string(str), maxLen))
+			}
+		}
 		return in
 	}
+	if isMeta {
+		// The keys of maps with fields that are accessed are all retained
+		if len(param.Children) > 0 {
+			return in
+		}
+		return metaValue(in, placeholder)
+	}
 	if isExists && len(param.Children) == 0 {
-		return data.String("")
+		return placeholder(in)
+	}
+	return extract(in, param.Children, placeholder)
+}
+
+// metaValue returns a value with the same metadata as a value that is not a list:
+// maps retain their keys with null values, null values are retained and any other
+// value is replaced with a placeholder.
+func metaValue(in data.Value, placeholder existencePlaceholder) data.Value {
+	switch v := in.(type) {
+	case data.Null, data.Undefined:
+		return in
+	case data.Map:
+		out := make(data.Map, len(v))
+		for key := range v {
+			out[key] = data.Null{}
+		}
+		return out
+	}
+	return placeholder(in)
+}
+
+// truncatedLength returns the length to which string values of this param may be truncated
+// without affecting the output of the template.
+// This is only possible if every usage prints the param using the truncate directive.
+func (p *Param) truncatedLength() (int, bool) {
+	var maxLen int
+	for _, usage := range p.Usage {
+		length, truncated := truncateDirectiveLength(usage)
+		if !truncated {
+			return 0, false
+		}
+		if length > maxLen {
+			maxLen = length
+		}
+	}
+	return maxLen, len(p.Usage) > 0
+}
+
+// truncateDirectiveLength returns the maximum length set by a truncate directive
+// for a usage that prints a param.
+func truncateDirectiveLength(usage Usage) (int, bool) {
+	if usage.Type != UsageFull {
+		return 0, false
+	}
+	for _, directive := range usage.directives {
+		if directive.Name == "truncate" {
+			if len(directive.Args) == 0 {
+				return 0, false
+			}
+			length, isInt := directive.Args[0].(*ast.IntNode)
+			if !isInt || length.Value < 0 {
+				return 0, false
+			}
+			return int(length.Value), true
+		}
+		if _, preserved := prefixPreservingDirectives[directive.Name]; !preserved {
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// truncateString shortens a string that would be truncated to maxLen bytes, keeping
+// enough of the string that truncation produces the same result.
+func truncateString(str string, maxLen int) string {
+	var keep = maxLen + 1
+	if len(str) <= keep {
+		return str
+	}
+	for keep < len(str) && !utf8.RuneStart(str[keep]) {
+		keep++
 	}
-	return Extract(in, param.Children)
+	return str[:keep]
 }
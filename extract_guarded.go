@@ -0,0 +1,321 @@
+package soyusage
+
+import (
+	"github.com/yext/soy/ast"
+	"github.com/yext/soy/data"
+)
+
+// maxGuardDepth limits the depth of the search for the value of a param referenced by a guard
+const maxGuardDepth = 8
+
+// ExtractGuarded returns a version of the input data containing only the values
+// specified in the provided usage analysis whose guards can be satisfied by the input.
+//
+// Guards from if statements, switch cases, the branches of ternaries and the second
+// argument of and and or are evaluated against the input data when they consist of
+// data refs, constants, equality, negation and logical operators.
+// Usages with a guard that is known to be false are ignored, so fields that are only
+// read in branches that will not be rendered are removed. Guards that cannot be evaluated
+// are assumed to be satisfied.
+// Values are otherwise extracted in the same way as Extract.
+func ExtractGuarded(in data.Value, params Params) data.Value {
+	inMap, isMap := in.(data.Map)
+	if !isMap {
+		return in
+	}
+	e := &guardedExtractor{
+		frames: []guardFrame{{param: &Param{Children: params}, value: inMap}},
+		paths:  make(map[[2]*Param]guardPath),
+	}
+	return e.extract(inMap, params)
+}
+
+// guardedExtractor holds the state of a guarded extraction
+type guardedExtractor struct {
+	// frames holds the values of each param on the path to the value being extracted
+	frames []guardFrame
+	// paths caches the path between params
+	paths map[[2]*Param]guardPath
+}
+
+type guardFrame struct {
+	param *Param
+	value data.Value
+	// element is set for the frame of a single element of a list
+	element bool
+}
+
+type guardPath struct {
+	path  []string
+	found bool
+}
+
+func (e *guardedExtractor) push(param *Param, value data.Value, element bool) {
+	e.frames = append(e.frames, guardFrame{param: param, value: value, element: element})
+}
+
+func (e *guardedExtractor) pop() {
+	e.frames = e.frames[:len(e.frames)-1]
+}
+
+// extract keeps the values for each of the provided params
+func (e *guardedExtractor) extract(in data.Value, params Params) data.Value {
+	inMap, isMap := in.(data.Map)
+	if !isMap {
+		return in
+	}
+	var out = make(data.Map)
+	for key, value := range inMap {
+		if param, exists := paramForKey(params, key); exists {
+			if outVal := e.extractParam(param, value, false); outVal != nil {
+				out[key] = outVal
+			}
+		}
+	}
+	return out
+}
+
+// extractParam keeps the parts of a value used by a param, if any of its usages may occur
+func (e *guardedExtractor) extractParam(param *Param, in data.Value, element bool) data.Value {
+	if in == nil {
+		return nil
+	}
+	if listValue, isList := in.(data.List); isList {
+		e.push(param, in, element)
+		defer e.pop()
+		var (
+			outList data.List
+			used    bool
+		)
+		for _, value := range listValue {
+			outValue := e.extractParam(param, value, true)
+			used = used || outValue != nil
+			outList = append(outList, outValue)
+		}
+		if !used && len(listValue) > 0 {
+			return nil
+		}
+		return outList
+	}
+
+	e.push(param, in, element)
+	defer e.pop()
+	if !e.used(param) {
+		return nil
+	}
+	var isFull, isExists bool
+	for _, usage := range param.Usage {
+		if !e.satisfied(usage) {
+			continue
+		}
+		switch usage.Type {
+		case UsageFull, UsageUnknown, UsageMeta:
+			isFull = true
+		case UsageExists:
+			isExists = true
+		}
+	}
+	if isFull {
+		return extractParam(param, in, emptyString)
+	}
+	if isExists && len(param.Children) == 0 {
+		return emptyString(in)
+	}
+	return e.extract(in, param.Children)
+}
+
+// used returns true if any usage of a param or its descendants may occur
+func (e *guardedExtractor) used(param *Param) bool {
+	if len(param.Usage) == 0 && len(param.Children) == 0 {
+		return true
+	}
+	for _, usage := range param.Usage {
+		if e.satisfied(usage) {
+			return true
+		}
+	}
+	for _, child := range param.Children {
+		if e.used(child) {
+			return true
+		}
+	}
+	return false
+}
+
+// satisfied returns true unless one of the guards for a usage is known to be false
+func (e *guardedExtractor) satisfied(usage Usage) bool {
+	for _, guard := range usage.guards {
+		if !e.holds(guard) {
+			return false
+		}
+	}
+	return true
+}
+
+// holds returns false if a guard is known to be false
+func (e *guardedExtractor) holds(guard Guard) bool {
+	value, known := e.eval(guard, guard.Node)
+	if !known {
+		return true
+	}
+	if !guard.isSwitch() {
+		return value.Truthy() != guard.Negated
+	}
+	for _, caseNode := range guard.Values {
+		caseValue, known := e.eval(guard, caseNode)
+		if !known {
+			return true
+		}
+		if equalValues(value, caseValue) {
+			return !guard.Negated
+		}
+	}
+	return guard.Negated
+}
+
+// eval evaluates an expression from a guard, returning false if the value is not known
+func (e *guardedExtractor) eval(guard Guard, node ast.Node) (data.Value, bool) {
+	switch v := node.(type) {
+	case *ast.StringNode:
+		return data.String(v.Value), true
+	case *ast.IntNode:
+		return data.Int(v.Value), true
+	case *ast.FloatNode:
+		return data.Float(v.Value), true
+	case *ast.BoolNode:
+		return data.Bool(v.True), true
+	case *ast.NullNode:
+		return data.Null{}, true
+	case *ast.DataRefNode:
+		params := guard.refs[v]
+		if len(params) != 1 {
+			return nil, false
+		}
+		return e.lookup(params[0])
+	case *ast.NotNode:
+		arg, known := e.eval(guard, v.Arg)
+		if !known {
+			return nil, false
+		}
+		return data.Bool(!arg.Truthy()), true
+	case *ast.AndNode:
+		arg1, known1 := e.eval(guard, v.Arg1)
+		arg2, known2 := e.eval(guard, v.Arg2)
+		if known1 && !arg1.Truthy() || known2 && !arg2.Truthy() {
+			return data.Bool(false), true
+		}
+		return data.Bool(true), known1 && known2
+	case *ast.OrNode:
+		arg1, known1 := e.eval(guard, v.Arg1)
+		arg2, known2 := e.eval(guard, v.Arg2)
+		if known1 && arg1.Truthy() || known2 && arg2.Truthy() {
+			return data.Bool(true), true
+		}
+		return data.Bool(false), known1 && known2
+	case *ast.EqNode:
+		arg1, known1 := e.eval(guard, v.Arg1)
+		arg2, known2 := e.eval(guard, v.Arg2)
+		return data.Bool(known1 && known2 && equalValues(arg1, arg2)), known1 && known2
+	case *ast.NotEqNode:
+		arg1, known1 := e.eval(guard, v.Arg1)
+		arg2, known2 := e.eval(guard, v.Arg2)
+		return data.Bool(known1 && known2 && !equalValues(arg1, arg2)), known1 && known2
+	}
+	return nil, false
+}
+
+// lookup finds the value of a param in the input data, using the innermost frame
+// containing the param. Values within the elements of a list iterated by nested loops
+// are not known, as loop variables may refer to different elements.
+func (e *guardedExtractor) lookup(param *Param) (data.Value, bool) {
+	if param.isConstant() {
+		if _, isNonConstant := param.constant.(nonConstant); isNonConstant {
+			return nil, false
+		}
+		return data.New(param.constant), true
+	}
+	for i := len(e.frames) - 1; i >= 0; i-- {
+		frame := e.frames[i]
+		path := e.pathTo(frame.param, param)
+		if !path.found {
+			continue
+		}
+		// A reference to a list param may refer to the list itself, or its elements
+		if len(path.path) == 0 && frame.element {
+			return nil, false
+		}
+		if frame.element && frame.param.nestedIteration {
+			return nil, false
+		}
+		var value = frame.value
+		for _, name := range path.path {
+			switch v := value.(type) {
+			case data.Map:
+				value = v[name]
+			case data.List:
+				return nil, false
+			default:
+				value = nil
+			}
+		}
+		if value == nil {
+			return data.Null{}, true
+		}
+		return value, true
+	}
+	return nil, false
+}
+
+// pathTo finds the names of the fields leading from one param to another
+func (e *guardedExtractor) pathTo(from, to *Param) guardPath {
+	key := [2]*Param{from, to}
+	if path, cached := e.paths[key]; cached {
+		return path
+	}
+	var path = guardPath{}
+	if from == to {
+		path.found = true
+	} else if names, found := findParam(from, to, maxGuardDepth, make(map[*Param]struct{})); found {
+		path = guardPath{path: names, found: true}
+	}
+	e.paths[key] = path
+	return path
+}
+
+func findParam(from, to *Param, depth int, visited map[*Param]struct{}) ([]string, bool) {
+	if depth == 0 {
+		return nil, false
+	}
+	if _, seen := visited[from]; seen {
+		return nil, false
+	}
+	visited[from] = struct{}{}
+	for name, child := range from.Children {
+		if _, isName := name.(Name); !isName {
+			continue
+		}
+		if child == to {
+			return []string{name.String()}, true
+		}
+		if path, found := findParam(child, to, depth-1, visited); found {
+			return append([]string{name.String()}, path...), true
+		}
+	}
+	return nil, false
+}
+
+// equalValues compares values in the same way as soy, treating missing values as null
+func equalValues(a, b data.Value) bool {
+	if isNullish(a) && isNullish(b) {
+		return true
+	}
+	return a.Equals(b)
+}
+
+func isNullish(v data.Value) bool {
+	switch v.(type) {
+	case data.Null, data.Undefined:
+		return true
+	}
+	return false
+}
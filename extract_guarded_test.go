@@ -0,0 +1,247 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func TestExtractGuarded(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		in           data.Value
+		expected     data.Value
+	}{
+		{
+			name: "switch cases on a discriminator",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entities
+				*/
+				{template .main}
+					{foreach $entity in $entities}
+						{switch $entity.type}
+							{case 'location'}
+								{$entity.address}
+							{case 'event', 'webinar'}
+								{$entity.time}
+							{default}
+								{$entity.name}
+						{/switch}
+					{/foreach}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"entities": []interface{}{
+					map[string]interface{}{"type": "location", "address": "a", "time": "t", "name": "n"},
+					map[string]interface{}{"type": "webinar", "address": "a", "time": "t", "name": "n"},
+					map[string]interface{}{"type": "person", "address": "a", "time": "t", "name": "n"},
+				},
+			}),
+			expected: data.New(map[string]interface{}{
+				"entities": []interface{}{
+					map[string]interface{}{"type": "location", "address": "a"},
+					map[string]interface{}{"type": "webinar", "time": "t"},
+					map[string]interface{}{"type": "person", "name": "n"},
+				},
+			}),
+		},
+		{
+			name: "if conditions",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				* @param mode
+				*/
+				{template .main}
+					{if $loc.closed}
+						{$loc.closedMessage}
+					{elseif $mode == 'full' and not $loc.hidden}
+						{$loc.description}
+					{else}
+						{$loc.summary}
+					{/if}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"mode": "full",
+				"loc": map[string]interface{}{
+					"closed":        false,
+					"closedMessage": "closed",
+					"description":   "description",
+					"summary":       "summary",
+				},
+			}),
+			expected: data.New(map[string]interface{}{
+				"mode": "full",
+				"loc": map[string]interface{}{
+					"closed":      "",
+					"description": "description",
+				},
+			}),
+		},
+		{
+			name: "ternary branches and logical operators",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.featured ? $loc.badge : $loc.plain}
+					{if $loc.open and $loc.hours}Open{/if}
+					{$loc.closed or $loc.reason}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"featured": true,
+					"badge":    "badge",
+					"plain":    "plain",
+					"open":     false,
+					"hours":    "hours",
+					"closed":   true,
+					"reason":   "reason",
+				},
+			}),
+			expected: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"featured": true,
+					"badge":    "badge",
+					"open":     false,
+					"closed":   true,
+				},
+			}),
+		},
+		{
+			name: "guards that cannot be evaluated are satisfied",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{if length($loc.photos) > 1}
+						{$loc.gallery}
+					{/if}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"photos":  []interface{}{"a"},
+					"gallery": "gallery",
+				},
+			}),
+			expected: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"photos":  []interface{}{""},
+					"gallery": "gallery",
+				},
+			}),
+		},
+		{
+			name: "guards within nested loops over the same list",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param items
+				*/
+				{template .main}
+					{foreach $a in $items}
+						{foreach $b in $items}
+							{if $a.show}{$b.name}{/if}
+						{/foreach}
+					{/foreach}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"show": true, "name": "a"},
+					map[string]interface{}{"show": false, "name": "b"},
+				},
+			}),
+			expected: data.New(map[string]interface{}{
+				"items": []interface{}{
+					map[string]interface{}{"show": "", "name": "a"},
+					map[string]interface{}{"show": "", "name": "b"},
+				},
+			}),
+		},
+		{
+			name: "guards apply within called templates",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{if $loc.kind == 'store'}
+						{call .store}
+							{param store: $loc /}
+						{/call}
+					{/if}
+				{/template}
+
+				/**
+				* @param store
+				*/
+				{template .store}
+					{$store.hours}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"kind":  "office",
+					"hours": "9-5",
+				},
+			}),
+			expected: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"kind": "office",
+				},
+			}),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			must.BeEqual(t, test.expected, soyusage.ExtractGuarded(test.in, params))
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package soyusage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ExtractJSON reads JSON data from r and writes a version containing only the values
+// specified in the provided usage analysis to w.
+//
+// The output is equivalent to that of Extract, but the input is processed as a stream
+// of tokens: subtrees that are not used are skipped without being loaded into memory.
+// Object keys are written in the order they appear in the input.
+func ExtractJSON(r io.Reader, w io.Writer, params Params) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	e := &jsonExtractor{
+		decoder: decoder,
+		w:       bufio.NewWriter(w),
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if err := e.extract(params, token); err != nil {
+		return err
+	}
+	return e.w.Flush()
+}
+
+type jsonExtractor struct {
+	decoder *json.Decoder
+	w       *bufio.Writer
+}
+
+// extract processes the value starting with token, keeping only the fields in params
+func (e *jsonExtractor) extract(params Params, token json.Token) error {
+	if token != json.Delim('{') {
+		return e.copy(token)
+	}
+
+	e.w.WriteByte('{')
+	var first = true
+	for e.decoder.More() {
+		key, err := e.key()
+		if err != nil {
+			return err
+		}
+		param, exists := paramForKey(params, key)
+		next, err := e.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			if err := e.skip(next); err != nil {
+				return err
+			}
+			continue
+		}
+		if !first {
+			e.w.WriteByte(',')
+		}
+		first = false
+		if err := e.writeJSON(key); err != nil {
+			return err
+		}
+		e.w.WriteByte(':')
+		if err := e.extractParam(param, next); err != nil {
+			return err
+		}
+	}
+	return e.end('}')
+}
+
+// extractParam processes the value starting with token according to the usage of param
+func (e *jsonExtractor) extractParam(param *Param, token json.Token) error {
+	if token == json.Delim('[') {
+		e.w.WriteByte('[')
+		for first := true; e.decoder.More(); first = false {
+			if !first {
+				e.w.WriteByte(',')
+			}
+			next, err := e.decoder.Token()
+			if err != nil {
+				return err
+			}
+			if err := e.extractParam(param, next); err != nil {
+				return err
+			}
+		}
+		return e.end(']')
+	}
+
+	var (
+		isFull   bool
+		isExists bool
+		isMeta   bool
+	)
+	for _, usage := range param.Usage {
+		switch usage.Type {
+		case UsageFull, UsageUnknown:
+			isFull = true
+		case UsageMeta:
+			isMeta = true
+		case UsageExists:
+			isExists = true
+		}
+	}
+	if isFull {
+		if maxLen, truncatable := param.truncatedLength(); truncatable {
+			if str, isString := token.(string); isString {
+				return e.writeJSON(truncateString(str, maxLen))
+			}
+		}
+		return e.copy(token)
+	}
+	if isMeta {
+		if len(param.Children) > 0 {
+			return e.copy(token)
+		}
+		return e.meta(token)
+	}
+	if isExists && len(param.Children) == 0 {
+		if err := e.skip(token); err != nil {
+			return err
+		}
+		_, err := e.w.WriteString(`""`)
+		return err
+	}
+	return e.extract(param.Children, token)
+}
+
+// meta writes a value with the same metadata as the value starting with token, as for metaValue
+func (e *jsonExtractor) meta(token json.Token) error {
+	switch token {
+	case nil:
+		_, err := e.w.WriteString("null")
+		return err
+	case json.Delim('{'):
+		e.w.WriteByte('{')
+		for first := true; e.decoder.More(); first = false {
+			if !first {
+				e.w.WriteByte(',')
+			}
+			key, err := e.key()
+			if err != nil {
+				return err
+			}
+			if err := e.writeJSON(key); err != nil {
+				return err
+			}
+			e.w.WriteString(":null")
+			next, err := e.decoder.Token()
+			if err != nil {
+				return err
+			}
+			if err := e.skip(next); err != nil {
+				return err
+			}
+		}
+		return e.end('}')
+	}
+	_, err := e.w.WriteString(`""`)
+	return err
+}
+
+// copy writes the value starting with token to the output unchanged
+func (e *jsonExtractor) copy(token json.Token) error {
+	switch token {
+	case json.Delim('{'):
+		e.w.WriteByte('{')
+		for first := true; e.decoder.More(); first = false {
+			if !first {
+				e.w.WriteByte(',')
+			}
+			key, err := e.key()
+			if err != nil {
+				return err
+			}
+			if err := e.writeJSON(key); err != nil {
+				return err
+			}
+			e.w.WriteByte(':')
+			if err := e.copyNext(); err != nil {
+				return err
+			}
+		}
+		return e.end('}')
+	case json.Delim('['):
+		e.w.WriteByte('[')
+		for first := true; e.decoder.More(); first = false {
+			if !first {
+				e.w.WriteByte(',')
+			}
+			if err := e.copyNext(); err != nil {
+				return err
+			}
+		}
+		return e.end(']')
+	}
+	if number, isNumber := token.(json.Number); isNumber {
+		_, err := e.w.WriteString(number.String())
+		return err
+	}
+	return e.writeJSON(token)
+}
+
+func (e *jsonExtractor) copyNext() error {
+	token, err := e.decoder.Token()
+	if err != nil {
+		return err
+	}
+	return e.copy(token)
+}
+
+// skip discards the value starting with token.
+// Skipping token by token is faster than decoding the value into a json.RawMessage or
+// json.Unmarshaler, which also requires the entire value to be held in memory.
+func (e *jsonExtractor) skip(token json.Token) error {
+	var depth int
+	for {
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if token, err = e.decoder.Token(); err != nil {
+			return err
+		}
+	}
+}
+
+// key reads the next key of an object
+func (e *jsonExtractor) key() (string, error) {
+	token, err := e.decoder.Token()
+	if err != nil {
+		return "", err
+	}
+	key, isString := token.(string)
+	if !isString {
+		return "", fmt.Errorf("unexpected object key: %v", token)
+	}
+	return key, nil
+}
+
+// end reads the closing delimiter of an object or list and writes it to the output
+func (e *jsonExtractor) end(delim json.Delim) error {
+	token, err := e.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return e.w.WriteByte(byte(delim))
+}
+
+func (e *jsonExtractor) writeJSON(value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(content)
+	return err
+}
@@ -0,0 +1,218 @@
+package soyusage_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func TestExtractJSON(t *testing.T) {
+	for _, test := range extractTests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.recursionDepth == 0 {
+				test.recursionDepth = 2
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry, soyusage.Recursion(test.recursionDepth))
+			if err != nil {
+				t.Fatal(err)
+			}
+			in, err := json.Marshal(test.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := soyusage.ExtractJSON(bytes.NewReader(in), &out, params); err != nil {
+				t.Fatal(err)
+			}
+			must.BeEqual(t, jsonRoundTrip(t, test.expected), jsonDecode(t, out.Bytes()))
+		})
+	}
+}
+
+func TestExtractJSONPreservesInput(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param a
+	* @param list
+	*/
+	{template .main}
+		{$a.b}
+		{foreach $item in $list}
+			{if $item.c}{/if}
+		{/foreach}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	err = soyusage.ExtractJSON(strings.NewReader(`{
+		"unused": {"deep": [1, 2, {"x": null}]},
+		"a": {"z": 1, "b": {"n": 12345678901234567890, "s": "<tag>"}},
+		"list": [{"c": {"big": true}, "d": 1}, {"d": 2}, null]
+	}`), &out, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, `{"a":{"b":{"n":12345678901234567890,"s":"\u003ctag\u003e"}},"list":[{"c":""},{},null]}`, out.String())
+
+	err = soyusage.ExtractJSON(strings.NewReader(`{"a": {"b": [1, 2`), &out, params)
+	if err == nil {
+		t.Error("expected error for truncated input")
+	}
+}
+
+func jsonRoundTrip(t *testing.T, value interface{}) interface{} {
+	t.Helper()
+	content, err := json.Marshal(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return jsonDecode(t, content)
+}
+
+func jsonDecode(t *testing.T, content []byte) interface{} {
+	t.Helper()
+	var out interface{}
+	if err := json.Unmarshal(content, &out); err != nil {
+		t.Fatalf("%v: %s", err, content)
+	}
+	return out
+}
+
+// benchmarkTemplate uses a small part of the payload produced by benchmarkPayload
+const benchmarkTemplate = `
+{namespace bench}
+/**
+* @param entity
+* @param site
+*/
+{template .main}
+	{$entity.name}
+	{$entity.address.city}
+	{foreach $review in $entity.reviews}
+		{$review.rating}
+	{/foreach}
+	{if $entity.photos}Photos{/if}
+	{$site.domain}
+{/template}
+`
+
+// benchmarkPayload returns a JSON payload resembling the data for an entity page,
+// most of which is not used by benchmarkTemplate
+func benchmarkPayload(b *testing.B) []byte {
+	b.Helper()
+	text := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 20)
+	var reviews, photos, links []interface{}
+	for i := 0; i < 500; i++ {
+		reviews = append(reviews, map[string]interface{}{
+			"rating": i % 5,
+			"text":   text,
+			"author": map[string]interface{}{"name": "Author", "id": i},
+			"date":   "2024-01-01T00:00:00Z",
+		})
+	}
+	for i := 0; i < 200; i++ {
+		photos = append(photos, map[string]interface{}{
+			"url":     "https://example.com/photo.jpg",
+			"width":   1024,
+			"height":  768,
+			"caption": text[:100],
+		})
+	}
+	for i := 0; i < 100; i++ {
+		links = append(links, map[string]interface{}{"label": "Link", "url": "https://example.com/"})
+	}
+	content, err := json.Marshal(map[string]interface{}{
+		"entity": map[string]interface{}{
+			"name":        "Store",
+			"description": strings.Repeat(text, 10),
+			"address":     map[string]interface{}{"line1": "1 Main St", "city": "Springfield", "region": "IL"},
+			"reviews":     reviews,
+			"photos":      photos,
+			"hours": map[string]interface{}{
+				"mon": map[string]interface{}{"open": "9", "close": "5"},
+				"tue": map[string]interface{}{"open": "9", "close": "5"},
+			},
+		},
+		"site": map[string]interface{}{
+			"domain": "example.com",
+			"nav":    links,
+		},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return content
+}
+
+func benchmarkParams(b *testing.B) soyusage.Params {
+	b.Helper()
+	registry, err := soy.NewBundle().AddTemplateString("bench.soy", benchmarkTemplate).Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("bench.main", registry)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return params
+}
+
+// BenchmarkExtract decodes a JSON payload, extracts it and encodes the result,
+// for comparison with BenchmarkExtractJSON
+func BenchmarkExtract(b *testing.B) {
+	var (
+		params  = benchmarkParams(b)
+		payload = benchmarkPayload(b)
+	)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var in interface{}
+		if err := json.Unmarshal(payload, &in); err != nil {
+			b.Fatal(err)
+		}
+		out := soyusage.Extract(data.New(in), params)
+		if _, err := json.Marshal(out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExtractJSON(b *testing.B) {
+	var (
+		params  = benchmarkParams(b)
+		payload = benchmarkPayload(b)
+	)
+	b.SetBytes(int64(len(payload)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := soyusage.ExtractJSON(bytes.NewReader(payload), io.Discard, params); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -10,12 +10,11 @@ import (
 	"github.com/yext/soy/data"
 )
 
-func TestExtract(t *testing.T) {
-	var tests = []extractTest{
-		{
-			name: "missing params are ignored",
-			templates: map[string]string{
-				"test.soy": `
+var extractTests = []extractTest{
+	{
+		name: "missing params are ignored",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param a
@@ -24,19 +23,19 @@ func TestExtract(t *testing.T) {
 					{$a.b | json}
 				{/template}
 			`,
-			},
-			templateName: "test.main",
-			in: data.New(map[string]interface{}{
-				"a": map[string]interface{}{},
-			}),
-			expected: data.New(map[string]interface{}{
-				"a": map[string]interface{}{},
-			}),
 		},
-		{
-			name: "handles if",
-			templates: map[string]string{
-				"test.soy": `
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"a": map[string]interface{}{},
+		}),
+		expected: data.New(map[string]interface{}{
+			"a": map[string]interface{}{},
+		}),
+	},
+	{
+		name: "handles if",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param a
@@ -48,24 +47,24 @@ func TestExtract(t *testing.T) {
 					{/if}
 				{/template}
 			`,
-			},
-			templateName: "test.main",
-			in: data.New(map[string]interface{}{
-				"a": map[string]interface{}{
-					"item1": "value1",
-					"item2": "value2",
-				},
-				"b": "bvalue",
-			}),
-			expected: data.New(map[string]interface{}{
-				"a": "",
-				"b": "bvalue",
-			}),
 		},
-		{
-			name: "iteration is handled",
-			templates: map[string]string{
-				"test.soy": `
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"item1": "value1",
+				"item2": "value2",
+			},
+			"b": "bvalue",
+		}),
+		expected: data.New(map[string]interface{}{
+			"a": "",
+			"b": "bvalue",
+		}),
+	},
+	{
+		name: "iteration is handled",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param list
@@ -76,35 +75,35 @@ func TestExtract(t *testing.T) {
 					{/foreach}
 				{/template}
 			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"value":  1,
+					"unused": "ignore1",
+				},
+				map[string]interface{}{
+					"value":  2,
+					"unused": "ignore2",
+				},
 			},
-			templateName: "test.main",
-			in: data.New(map[string]interface{}{
-				"list": []interface{}{
-					map[string]interface{}{
-						"value":  1,
-						"unused": "ignore1",
-					},
-					map[string]interface{}{
-						"value":  2,
-						"unused": "ignore2",
-					},
+		}),
+		expected: data.New(map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{
+					"value": 1,
 				},
-			}),
-			expected: data.New(map[string]interface{}{
-				"list": []interface{}{
-					map[string]interface{}{
-						"value": 1,
-					},
-					map[string]interface{}{
-						"value": 2,
-					},
+				map[string]interface{}{
+					"value": 2,
 				},
-			}),
-		},
-		{
-			name: "unknown map index",
-			templates: map[string]string{
-				"test.soy": `
+			},
+		}),
+	},
+	{
+		name: "unknown map index",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param map
@@ -114,35 +113,35 @@ func TestExtract(t *testing.T) {
 					{$map[$index].value}
 				{/template}
 			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"map": map[string]interface{}{
+				"a": map[string]interface{}{
+					"value":  1,
+					"unused": "ignore1",
+				},
+				"b": map[string]interface{}{
+					"value":  2,
+					"unused": "ignore2",
+				},
 			},
-			templateName: "test.main",
-			in: data.New(map[string]interface{}{
-				"map": map[string]interface{}{
-					"a": map[string]interface{}{
-						"value":  1,
-						"unused": "ignore1",
-					},
-					"b": map[string]interface{}{
-						"value":  2,
-						"unused": "ignore2",
-					},
+		}),
+		expected: data.New(map[string]interface{}{
+			"map": map[string]interface{}{
+				"a": map[string]interface{}{
+					"value": 1,
 				},
-			}),
-			expected: data.New(map[string]interface{}{
-				"map": map[string]interface{}{
-					"a": map[string]interface{}{
-						"value": 1,
-					},
-					"b": map[string]interface{}{
-						"value": 2,
-					},
+				"b": map[string]interface{}{
+					"value": 2,
 				},
-			}),
-		},
-		{
-			name: "removes unused parameters",
-			templates: map[string]string{
-				"test.soy": `
+			},
+		}),
+	},
+	{
+		name: "removes unused parameters",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param a
@@ -151,25 +150,25 @@ func TestExtract(t *testing.T) {
 					{$a.b | json}
 				{/template}
 			`,
-			},
-			templateName: "test.main",
-			in: data.New(map[string]interface{}{
-				"a": map[string]interface{}{
-					"b": "value",
-					"c": "not used",
-				},
-				"d": "also not used",
-			}),
-			expected: data.New(map[string]interface{}{
-				"a": map[string]interface{}{
-					"b": "value",
-				},
-			}),
 		},
-		{
-			name: "print outputs complete structure",
-			templates: map[string]string{
-				"test.soy": `
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"b": "value",
+				"c": "not used",
+			},
+			"d": "also not used",
+		}),
+		expected: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"b": "value",
+			},
+		}),
+	},
+	{
+		name: "print outputs complete structure",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param a
@@ -178,26 +177,26 @@ func TestExtract(t *testing.T) {
 					{$a}
 				{/template}
 			`,
-			},
-			templateName: "test.main",
-			in: data.New(map[string]interface{}{
-				"a": map[string]interface{}{
-					"b": "value",
-					"c": "another value",
-				},
-				"d": "also not used",
-			}),
-			expected: data.New(map[string]interface{}{
-				"a": map[string]interface{}{
-					"b": "value",
-					"c": "another value",
-				},
-			}),
 		},
-		{
-			name: "applies recursion up to limit",
-			templates: map[string]string{
-				"test.soy": `
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"b": "value",
+				"c": "another value",
+			},
+			"d": "also not used",
+		}),
+		expected: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"b": "value",
+				"c": "another value",
+			},
+		}),
+	},
+	{
+		name: "applies recursion up to limit",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param data
@@ -210,46 +209,46 @@ func TestExtract(t *testing.T) {
 					{/call}
 				{/template}
 			`,
-			},
-			templateName: "test.callee",
-			in: data.New(map[string]interface{}{
+		},
+		templateName: "test.callee",
+		in: data.New(map[string]interface{}{
+			"data": map[string]interface{}{
 				"data": map[string]interface{}{
 					"data": map[string]interface{}{
 						"data": map[string]interface{}{
-							"data": map[string]interface{}{
-								"value":  "level 4",
-								"unused": "4th unused",
-							},
-							"value":  "level 3",
-							"unused": "3rd unused",
+							"value":  "level 4",
+							"unused": "4th unused",
 						},
-						"value":  "level 2",
-						"unused": "2nd unused",
+						"value":  "level 3",
+						"unused": "3rd unused",
 					},
+					"value":  "level 2",
+					"unused": "2nd unused",
 				},
-				"x": "another value",
-			}),
-			expected: data.New(map[string]interface{}{
+			},
+			"x": "another value",
+		}),
+		expected: data.New(map[string]interface{}{
+			"data": map[string]interface{}{
 				"data": map[string]interface{}{
 					"data": map[string]interface{}{
 						"data": map[string]interface{}{
-							"data": map[string]interface{}{
-								"value":  "level 4",
-								"unused": "4th unused",
-							},
-							"value":  "level 3",
-							"unused": "3rd unused",
+							"value":  "level 4",
+							"unused": "4th unused",
 						},
-						"value": "level 2",
+						"value":  "level 3",
+						"unused": "3rd unused",
 					},
+					"value": "level 2",
 				},
-				"x": "another value",
-			}),
-		},
-		{
-			name: "recurses to full depth",
-			templates: map[string]string{
-				"test.soy": `
+			},
+			"x": "another value",
+		}),
+	},
+	{
+		name: "recurses to full depth",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param data
@@ -262,45 +261,45 @@ func TestExtract(t *testing.T) {
 					{/call}
 				{/template}
 			`,
-			},
-			templateName: "test.callee",
-			in: data.New(map[string]interface{}{
+		},
+		templateName: "test.callee",
+		in: data.New(map[string]interface{}{
+			"data": map[string]interface{}{
 				"data": map[string]interface{}{
 					"data": map[string]interface{}{
 						"data": map[string]interface{}{
-							"data": map[string]interface{}{
-								"value":  "level 4",
-								"unused": "4th unused",
-							},
-							"value":  "level 3",
-							"unused": "3rd unused",
+							"value":  "level 4",
+							"unused": "4th unused",
 						},
-						"value":  "level 2",
-						"unused": "2nd unused",
+						"value":  "level 3",
+						"unused": "3rd unused",
 					},
+					"value":  "level 2",
+					"unused": "2nd unused",
 				},
-				"x": "another value",
-			}),
-			expected: data.New(map[string]interface{}{
+			},
+			"x": "another value",
+		}),
+		expected: data.New(map[string]interface{}{
+			"data": map[string]interface{}{
 				"data": map[string]interface{}{
 					"data": map[string]interface{}{
 						"data": map[string]interface{}{
-							"data": map[string]interface{}{
-								"value": "level 4",
-							},
-							"value": "level 3",
+							"value": "level 4",
 						},
-						"value": "level 2",
+						"value": "level 3",
 					},
+					"value": "level 2",
 				},
-				"x": "another value",
-			}),
-			recursionDepth: 5,
-		},
-		{
-			name: "handles combined constant and variable values",
-			templates: map[string]string{
-				"test.soy": `
+			},
+			"x": "another value",
+		}),
+		recursionDepth: 5,
+	},
+	{
+		name: "handles combined constant and variable values",
+		templates: map[string]string{
+			"test.soy": `
 				{namespace test}
 				/**
 				* @param profile
@@ -318,29 +317,280 @@ func TestExtract(t *testing.T) {
 					{$profile[$textField]}
 				{/template}
 			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"profile": map[string]interface{}{
+				"c_lifeAbout": "life about",
+				"second":      "value 2",
+				"third":       "value 3",
+			},
+			"locale":      "loc",
+			"alternative": "alt",
+		}),
+		expected: data.New(map[string]interface{}{
+			"profile": map[string]interface{}{
+				"c_lifeAbout": "life about",
+				"second":      "value 2",
+				"third":       "value 3",
+			},
+			"locale":      "loc",
+			"alternative": "alt",
+		}),
+	},
+	{
+		name: "truncates strings only printed with truncate",
+		templates: map[string]string{
+			"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				* @param b
+				* @param c
+				* @param d
+				*/
+				{template .main}
+					{$a.desc|truncate:5}
+					{$a.short|truncate:5}
+					{$a.unicode|truncate:3,false}
+					{$b|truncate:5}
+					{$b|escapeHtml|truncate:7}
+					{$c|truncate:2}
+					{$c}
+					{if $d}{$d|truncate:2}{/if}
+				{/template}
+			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"desc":    "a long description",
+				"short":   "short",
+				"unicode": "abééé",
 			},
-			templateName: "test.main",
-			in: data.New(map[string]interface{}{
-				"profile": map[string]interface{}{
-					"c_lifeAbout": "life about",
-					"second":      "value 2",
-					"third":       "value 3",
+			"b": "another long value",
+			"c": "printed in full",
+			"d": "checked for existence",
+		}),
+		expected: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"desc":    "a long",
+				"short":   "short",
+				"unicode": "abé",
+			},
+			"b": "another ",
+			"c": "printed in full",
+			"d": "checked for existence",
+		}),
+	},
+	{
+		name: "shortens values only used for their metadata",
+		templates: map[string]string{
+			"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				*/
+				{template .main}
+					{length($a.photos)}
+					{length(keys($a.hours))}
+					{if isNonnull($a.site)}Site{/if}
+					{if isNonnull($a.missing)}Missing{/if}
+					{foreach $phone in $a.phones}
+						{if isFirst($phone)}First{/if}
+					{/foreach}
+					{length($a.tags)}
+					{foreach $tag in $a.tags}
+						{$tag}
+					{/foreach}
+				{/template}
+			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"photos":  []interface{}{"a.jpg", "b.jpg"},
+				"hours":   map[string]interface{}{"mon": "9-5", "tue": map[string]interface{}{"open": "9"}},
+				"site":    "example.com",
+				"missing": nil,
+				"phones":  []interface{}{map[string]interface{}{"number": "1"}, nil},
+				"tags":    []interface{}{"new"},
+			},
+		}),
+		expected: data.New(map[string]interface{}{
+			"a": map[string]interface{}{
+				"photos":  []interface{}{"", ""},
+				"hours":   map[string]interface{}{"mon": nil, "tue": nil},
+				"site":    "",
+				"missing": nil,
+				"phones":  []interface{}{map[string]interface{}{"number": nil}, nil},
+				"tags":    []interface{}{"new"},
+			},
+		}),
+	},
+	{
+		name: "retains map keys accessed via constant keys",
+		templates: map[string]string{
+			"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param field
+				*/
+				{template .main}
+					{switch $field}
+						{case 'c_about', 'c_name'}
+							{$profile[$field]}
+					{/switch}
+				{/template}
+			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"field": "c_about",
+			"profile": map[string]interface{}{
+				"c_about": map[string]interface{}{"text": "about"},
+				"c_name":  "name",
+				"c_other": "other",
+			},
+		}),
+		expected: data.New(map[string]interface{}{
+			"field": "c_about",
+			"profile": map[string]interface{}{
+				"c_about": map[string]interface{}{"text": "about"},
+				"c_name":  "name",
+			},
+		}),
+	},
+	{
+		name: "combines named and non-constant map keys",
+		templates: map[string]string{
+			"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param field
+				*/
+				{template .main}
+					{$profile.c_about.title}
+					{$profile[$field].text}
+					{$profile[$field + '_list']}
+				{/template}
+			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"field": "c_about",
+			"profile": map[string]interface{}{
+				"c_about": map[string]interface{}{
+					"title": "title",
+					"text":  "text",
+					"other": "other",
 				},
-				"locale":      "loc",
-				"alternative": "alt",
-			}),
-			expected: data.New(map[string]interface{}{
-				"profile": map[string]interface{}{
-					"c_lifeAbout": "life about",
-					"second":      "value 2",
-					"third":       "value 3",
+				"c_name": map[string]interface{}{
+					"title": "title",
+					"text":  "text",
 				},
-				"locale":      "loc",
-				"alternative": "alt",
-			}),
+				"c_about_list": []interface{}{
+					map[string]interface{}{"text": "text"},
+				},
+			},
+		}),
+		expected: data.New(map[string]interface{}{
+			"field": "c_about",
+			"profile": map[string]interface{}{
+				"c_about": map[string]interface{}{
+					"title": "title",
+					"text":  "text",
+				},
+				"c_name": map[string]interface{}{
+					"text": "text",
+				},
+				"c_about_list": []interface{}{
+					map[string]interface{}{"text": "text"},
+				},
+			},
+		}),
+	},
+	{
+		name: "keeps all keys read via a loop variable shadowing a switch value",
+		templates: map[string]string{
+			"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param field
+				* @param list
+				*/
+				{template .main}
+					{switch $field}
+						{case 'a'}
+							{foreach $field in $list}
+								{$profile[$field]}
+							{/foreach}
+					{/switch}
+				{/template}
+			`,
 		},
-	}
-	for _, test := range tests {
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"field": "a",
+			"list":  []interface{}{"x"},
+			"profile": map[string]interface{}{
+				"a": "A",
+				"x": "X",
+			},
+		}),
+		expected: data.New(map[string]interface{}{
+			"field": "a",
+			"list":  []interface{}{"x"},
+			"profile": map[string]interface{}{
+				"a": "A",
+				"x": "X",
+			},
+		}),
+	},
+	{
+		name: "keeps all keys read via a let shadowing a switch value",
+		templates: map[string]string{
+			"test.soy": `
+				{namespace test}
+				/**
+				* @param profile
+				* @param kind
+				* @param other
+				*/
+				{template .main}
+					{switch $kind}
+						{case 'a'}
+							{let $kind: $other /}
+							{$profile[$kind]}
+					{/switch}
+				{/template}
+			`,
+		},
+		templateName: "test.main",
+		in: data.New(map[string]interface{}{
+			"kind":  "a",
+			"other": "x",
+			"profile": map[string]interface{}{
+				"a": "A",
+				"x": "X",
+			},
+		}),
+		expected: data.New(map[string]interface{}{
+			"kind":  "a",
+			"other": "x",
+			"profile": map[string]interface{}{
+				"a": "A",
+				"x": "X",
+			},
+		}),
+	},
+}
+
+func TestExtract(t *testing.T) {
+	for _, test := range extractTests {
 		t.Run(test.name, func(t *testing.T) {
 			bundle := soy.NewBundle()
 			for name, content := range test.templates {
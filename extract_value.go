@@ -0,0 +1,235 @@
+package soyusage
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/yext/soy/data"
+)
+
+// structFieldCache maps a structFieldCacheKey to the fields of a struct
+// type, as a map[string]int from field name to field index.
+var structFieldCache sync.Map
+
+type structFieldCacheKey struct {
+	t          reflect.Type
+	lowerCamel bool
+}
+
+// ExtractValue returns a copy of a native Go value containing only the values
+// specified in the provided usage analysis. It performs the same pruning as Extract,
+// without first converting the value with data.New.
+//
+// Maps and slices are copied with unused entries omitted. Structs are copied with
+// unused fields set to their zero value, matching field names using the conversion
+// configured in data.DefaultStructOptions. Values that are only checked for existence
+// are replaced with an empty value of the same type and truthiness.
+// Values of types implementing data.Marshaler are returned unchanged, while data.Value
+// values are pruned with Extract.
+// The input value is not modified.
+func ExtractValue(v interface{}, params Params) interface{} {
+	if v == nil {
+		return nil
+	}
+	return extractReflect(reflect.ValueOf(v), params).Interface()
+}
+
+// extractReflect returns a value of the same type as v, containing only the fields in params
+func extractReflect(v reflect.Value, params Params) reflect.Value {
+	if out, handled := extractSpecial(v, func(in data.Value) data.Value {
+		return Extract(in, params)
+	}); handled {
+		return out
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return wrapReflect(v, func(elem reflect.Value) reflect.Value {
+			return extractReflect(elem, params)
+		})
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return v
+		}
+		out := reflect.MakeMap(v.Type())
+		iter := v.MapRange()
+		for iter.Next() {
+			if param, exists := paramForKey(params, iter.Key().String()); exists {
+				out.SetMapIndex(iter.Key(), extractParamReflect(param, iter.Value()))
+			}
+		}
+		return out
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		for name, index := range cachedStructFields(v.Type()) {
+			if param, exists := paramForKey(params, name); exists {
+				out.Field(index).Set(extractParamReflect(param, v.Field(index)))
+			}
+		}
+		return out
+	}
+	return v
+}
+
+// extractParamReflect returns a value of the same type as v, pruned according to the usage of param
+func extractParamReflect(param *Param, v reflect.Value) reflect.Value {
+	if out, handled := extractSpecial(v, func(in data.Value) data.Value {
+		return extractParam(param, in, emptyString)
+	}); handled {
+		return out
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		return wrapReflect(v, func(elem reflect.Value) reflect.Value {
+			return extractParamReflect(param, elem)
+		})
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(extractParamReflect(param, v.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			out.Index(i).Set(extractParamReflect(param, v.Index(i)))
+		}
+		return out
+	}
+
+	var (
+		isFull   bool
+		isExists bool
+		isMeta   bool
+	)
+	for _, usage := range param.Usage {
+		switch usage.Type {
+		case UsageFull, UsageUnknown:
+			isFull = true
+		case UsageMeta:
+			isMeta = true
+		case UsageExists:
+			isExists = true
+		}
+	}
+	if isFull {
+		if maxLen, truncatable := param.truncatedLength(); truncatable && v.Kind() == reflect.String {
+			out := reflect.New(v.Type()).Elem()
+			out.SetString(truncateString(v.String(), maxLen))
+			return out
+		}
+		return v
+	}
+	if isMeta {
+		if len(param.Children) > 0 {
+			return v
+		}
+		return metaReflect(v)
+	}
+	if isExists && len(param.Children) == 0 {
+		return emptyReflect(v)
+	}
+	return extractReflect(v, param.Children)
+}
+
+// extractSpecial handles values that cannot be traversed with reflection.
+// Soy data values are pruned using extract, while values implementing data.Marshaler
+// are returned unchanged.
+func extractSpecial(v reflect.Value, extract func(data.Value) data.Value) (reflect.Value, bool) {
+	if !v.IsValid() || !v.CanInterface() {
+		return v, true
+	}
+	if (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) && v.IsNil() {
+		return v, true
+	}
+	if v.Kind() != reflect.Interface && v.Type().Implements(valueType) {
+		out := reflect.ValueOf(extract(v.Interface().(data.Value)))
+		if out.IsValid() && out.Type().AssignableTo(v.Type()) {
+			return out, true
+		}
+		return v, true
+	}
+	if v.Kind() != reflect.Interface && v.Type().Implements(marshalerType) {
+		return v, true
+	}
+	return v, false
+}
+
+// wrapReflect applies f to the element of a pointer or interface value,
+// returning a new pointer or interface containing the result.
+func wrapReflect(v reflect.Value, f func(reflect.Value) reflect.Value) reflect.Value {
+	elem := f(v.Elem())
+	if v.Kind() == reflect.Ptr {
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out
+	}
+	out := reflect.New(v.Type()).Elem()
+	out.Set(elem)
+	return out
+}
+
+// emptyReflect returns an empty value of the same type as v that soy will consider to
+// have the same truthiness. Scalar values are returned unchanged.
+// metaReflect returns a value of the same type as v with the same metadata, as for metaValue
+func metaReflect(v reflect.Value) reflect.Value {
+	if v.Kind() != reflect.Map || v.IsNil() {
+		return emptyReflect(v)
+	}
+	out := reflect.MakeMapWithSize(v.Type(), v.Len())
+	zero := reflect.Zero(v.Type().Elem())
+	for _, key := range v.MapKeys() {
+		out.SetMapIndex(key, zero)
+	}
+	return out
+}
+
+func emptyReflect(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		return wrapReflect(v, emptyReflect)
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		return reflect.MakeMap(v.Type())
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		return reflect.MakeSlice(v.Type(), 0, 0)
+	case reflect.Struct, reflect.Array:
+		if v.Type() == timeType {
+			return v
+		}
+		return reflect.New(v.Type()).Elem()
+	}
+	return v
+}
+
+// cachedStructFields returns the index of each field of a struct type that will be
+// included when converted using data.New, by the name it will be given.
+func cachedStructFields(t reflect.Type) map[string]int {
+	key := structFieldCacheKey{t: t, lowerCamel: data.DefaultStructOptions.LowerCamel}
+	if fields, cached := structFieldCache.Load(key); cached {
+		return fields.(map[string]int)
+	}
+	var fields = make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		fields[structFieldKey(data.DefaultStructOptions, field.Name)] = i
+	}
+	structFieldCache.Store(key, fields)
+	return fields
+}
@@ -0,0 +1,183 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+type valueEntity struct {
+	Name    string
+	Closed  bool
+	Phones  []*valuePhone
+	Hours   map[string]valueHours
+	Details *valueDetails
+	Extra   interface{}
+	Raw     data.Value
+}
+
+type valuePhone struct {
+	Number    string
+	Extension string
+}
+
+type valueHours struct {
+	Open  string
+	Close string
+}
+
+type valueDetails struct {
+	Description string
+}
+
+func TestExtractValue(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param entity
+	* @param key
+	*/
+	{template .main}
+		{$entity.name}
+		{foreach $phone in $entity.phones}
+			{$phone.number}
+		{/foreach}
+		{$entity.hours[$key].open}
+		{if $entity.details}
+			Has details
+		{/if}
+		{$entity.extra.used}
+		{$entity.raw.used}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("structs", func(t *testing.T) {
+		in := &valueEntity{
+			Name:   "name",
+			Closed: true,
+			Phones: []*valuePhone{
+				{Number: "1", Extension: "2"},
+				nil,
+			},
+			Hours: map[string]valueHours{
+				"mon": {Open: "9", Close: "5"},
+			},
+			Details: &valueDetails{Description: "long description"},
+			Extra: map[string]interface{}{
+				"used":   "value",
+				"unused": "value",
+			},
+			Raw: data.Map{
+				"used":   data.String("value"),
+				"unused": data.String("value"),
+			},
+		}
+		got := soyusage.ExtractValue(map[string]interface{}{
+			"entity": in,
+			"key":    "mon",
+			"other":  "value",
+		}, params)
+		must.BeEqual(t, map[string]interface{}{
+			"entity": &valueEntity{
+				Name: "name",
+				Phones: []*valuePhone{
+					{Number: "1"},
+					nil,
+				},
+				Hours: map[string]valueHours{
+					"mon": {Open: "9"},
+				},
+				Details: &valueDetails{},
+				Extra: map[string]interface{}{
+					"used": "value",
+				},
+				Raw: data.Map{
+					"used": data.String("value"),
+				},
+			},
+			"key": "mon",
+		}, got)
+
+		// The input is unchanged
+		must.BeEqual(t, "long description", in.Details.Description)
+		must.BeEqual(t, "2", in.Phones[0].Extension)
+	})
+
+	t.Run("maps", func(t *testing.T) {
+		got := soyusage.ExtractValue(map[string]interface{}{
+			"entity": map[string]interface{}{
+				"name":    "name",
+				"closed":  true,
+				"details": map[string]interface{}{"description": "long description"},
+				"phones": []interface{}{
+					map[string]interface{}{"number": "1", "extension": "2"},
+				},
+			},
+		}, params)
+		must.BeEqual(t, map[string]interface{}{
+			"entity": map[string]interface{}{
+				"name":    "name",
+				"details": map[string]interface{}{},
+				"phones": []interface{}{
+					map[string]interface{}{"number": "1"},
+				},
+			},
+		}, got)
+	})
+}
+
+func TestExtractValueMetadata(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param entity
+	*/
+	{template .main}
+		{length($entity.phones)}
+		{length(keys($entity.hours))}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := soyusage.ExtractValue(map[string]interface{}{
+		"entity": &valueEntity{
+			Name: "name",
+			Phones: []*valuePhone{
+				{Number: "1", Extension: "2"},
+				nil,
+			},
+			Hours: map[string]valueHours{
+				"mon": {Open: "9", Close: "5"},
+			},
+		},
+	}, params)
+	must.BeEqual(t, map[string]interface{}{
+		"entity": &valueEntity{
+			Phones: []*valuePhone{
+				{},
+				nil,
+			},
+			Hours: map[string]valueHours{
+				"mon": {},
+			},
+		},
+	}, got)
+}
@@ -0,0 +1,100 @@
+package soyusage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FieldMaskConfig defines configurable options for generating FieldMask paths
+type FieldMaskConfig struct {
+	// SnakeCase converts each field name to snake_case, matching the proto field
+	// names used in FieldMask paths, instead of using names as they appear in templates.
+	SnakeCase bool
+}
+
+// FieldMaskOption defines a function that modifies the configuration for FieldMask generation
+type FieldMaskOption func(FieldMaskConfig) FieldMaskConfig
+
+// SnakeCase converts field names in generated FieldMask paths to snake_case
+func SnakeCase() FieldMaskOption {
+	return func(c FieldMaskConfig) FieldMaskConfig {
+		c.SnakeCase = true
+		return c
+	}
+}
+
+// FieldMaskPaths converts a parameter tree into a sorted list of paths suitable
+// for use in a protobuf FieldMask.
+//
+// FieldMask paths may not index into maps or repeated fields, so any field accessed
+// via a map index is collapsed to the path of the map itself. Repeated fields need
+// no special handling, as the parameter tree describes the fields of their elements.
+// Similarly, a field whose usage is UsageFull or UsageUnknown is requested in its
+// entirety and none of its children are listed. If the root params are accessed via a map
+// index, any field may be used, so the wildcard path "*" is returned alone.
+func FieldMaskPaths(params Params, options ...FieldMaskOption) []string {
+	var config FieldMaskConfig
+	for _, option := range options {
+		config = option(config)
+	}
+
+	if hasMapIndex(params) {
+		return []string{"*"}
+	}
+	var set = make(map[string]struct{})
+	addFieldMaskPaths(config, set, "", params)
+
+	var out = make([]string, 0, len(set))
+	for path := range set {
+		out = append(out, path)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func addFieldMaskPaths(config FieldMaskConfig, set map[string]struct{}, prefix string, params Params) {
+	for name, param := range params {
+		fieldName := name.String()
+		if config.SnakeCase {
+			fieldName = snakeCase(fieldName)
+		}
+		path := fieldName
+		if prefix != "" {
+			path = prefix + "." + fieldName
+		}
+		// Map entries cannot be selected individually
+		if len(param.Children) == 0 || param.usedEntirely() || hasMapIndex(param.Children) {
+			set[path] = struct{}{}
+			continue
+		}
+		addFieldMaskPaths(config, set, path, param.Children)
+	}
+}
+
+func hasMapIndex(params Params) bool {
+	_, exists := params[MapIndex{}]
+	return exists
+}
+
+// snakeCase converts a lowerCamel or UpperCamel name into snake_case.
+// Names that are already snake_case are left untouched.
+func snakeCase(name string) string {
+	var (
+		out   strings.Builder
+		runes = []rune(name)
+	)
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && runes[i-1] != '_' &&
+				(unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+					(i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				out.WriteRune('_')
+			}
+			out.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
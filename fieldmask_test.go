@@ -0,0 +1,172 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestFieldMaskPaths(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		options      []soyusage.FieldMaskOption
+		expected     []string
+	}{
+		{
+			name: "leaves are listed",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				* @param b
+				*/
+				{template .main}
+					{$a.b.c}
+					{$a.d}
+					{$b}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected:     []string{"a.b.c", "a.d", "b"},
+		},
+		{
+			name: "full usage collapses children",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				*/
+				{template .main}
+					{myFunc($a)}
+					{$a.b}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected:     []string{"a"},
+		},
+		{
+			name: "printing an object before its fields collapses children",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				*/
+				{template .main}
+					{$a|json}
+					{$a.b}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected:     []string{"a"},
+		},
+		{
+			name: "printing an object after its fields collapses children",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				*/
+				{template .main}
+					{$a.b}
+					{$a|json}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected:     []string{"a"},
+		},
+		{
+			name: "map index collapses to the map",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				* @param key
+				*/
+				{template .main}
+					{$a.values[$key].name}
+					{$a.values.first.name}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected:     []string{"a.values", "key"},
+		},
+		{
+			name: "list elements are transparent",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param list
+				*/
+				{template .main}
+					{foreach $item in $list}
+						{$item.name}
+					{/foreach}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected:     []string{"list.name"},
+		},
+		{
+			name: "snake case conversion",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				*/
+				{template .main}
+					{$entity.mainPhone}
+					{$entity.c_customField}
+					{$entity.websiteURL}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			options:      []soyusage.FieldMaskOption{soyusage.SnakeCase()},
+			expected:     []string{"entity.c_custom_field", "entity.main_phone", "entity.website_url"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			must.BeEqual(t, test.expected, soyusage.FieldMaskPaths(params, test.options...))
+		})
+	}
+}
+
+func TestFieldMaskPathsRootMapIndex(t *testing.T) {
+	params := soyusage.Params{
+		soyusage.Name("a"): &soyusage.Param{},
+		soyusage.MapIndex{}: &soyusage.Param{
+			Children: soyusage.Params{soyusage.Name("b"): &soyusage.Param{}},
+		},
+	}
+	must.BeEqual(t, []string{"*"}, soyusage.FieldMaskPaths(params))
+}
@@ -5,6 +5,8 @@ go 1.18
 require (
 	github.com/theothertomelliott/must v0.0.0-20180901182306-492b25fad7e5
 	github.com/yext/soy v0.0.1-alpha.1
+	google.golang.org/protobuf v1.28.1
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,176 @@
+package soyusage
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GenerateGoStructs generates Go source defining struct types that hold the
+// data used by a template, as described by its parameter tree.
+//
+// The root type is named typeName and has a field for each param. Field names
+// are chosen such that data.New will convert them back to the names used in the
+// templates. Fields with children become nested structs, fields accessed via a
+// map index become maps, and fields iterated in a foreach loop become slices.
+// Each field is documented with the templates that use it.
+func GenerateGoStructs(packageName, typeName string, params Params) ([]byte, error) {
+	g := &goGenerator{
+		names: make(map[string]struct{}),
+	}
+	g.names[typeName] = struct{}{}
+	g.queue = append(g.queue, namedParams{name: typeName, params: params})
+	for len(g.queue) > 0 {
+		next := g.queue[0]
+		g.queue = g.queue[1:]
+		g.writeStruct(next)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by soyusage. DO NOT EDIT.\n\npackage %s\n", packageName)
+	out.Write(g.body.Bytes())
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %v", err)
+	}
+	return formatted, nil
+}
+
+// namedParams associates a set of params with the name of the type that will hold them
+type namedParams struct {
+	name   string
+	params Params
+}
+
+type goGenerator struct {
+	body  bytes.Buffer
+	queue []namedParams
+	names map[string]struct{}
+}
+
+func (g *goGenerator) writeStruct(s namedParams) {
+	fmt.Fprintf(&g.body, "\ntype %s struct {\n", s.name)
+	for _, name := range sortedNames(s.params) {
+		if _, isMapIndex := name.(MapIndex); isMapIndex {
+			continue
+		}
+		param := s.params[name]
+		fieldName := goFieldName(name.String())
+		if fieldName == "" {
+			fmt.Fprintf(&g.body, "// %q cannot be represented as a struct field\n", name.String())
+			continue
+		}
+		if templates := usageTemplates(param); len(templates) > 0 {
+			fmt.Fprintf(&g.body, "// %s is used by %s.\n", fieldName, strings.Join(templates, ", "))
+		}
+		fmt.Fprintf(&g.body, "%s %s\n", fieldName, g.goType(s.name+fieldName, param))
+	}
+	fmt.Fprintf(&g.body, "}\n")
+}
+
+// goType returns the type to be used for a param, queueing any nested structs
+// that need to be generated.
+func (g *goGenerator) goType(name string, param *Param) string {
+	if param.iterated {
+		return "[]" + g.goValueType(name, param)
+	}
+	return g.goValueType(name, param)
+}
+
+func (g *goGenerator) goValueType(name string, param *Param) string {
+	if hasMapIndex(param.Children) {
+		return "map[string]" + g.goType(name+"Value", mergeChildren(param))
+	}
+	if len(param.Children) > 0 {
+		structName := g.uniqueName(name)
+		g.queue = append(g.queue, namedParams{name: structName, params: param.Children})
+		return "*" + structName
+	}
+	return goLeafType(param)
+}
+
+func (g *goGenerator) uniqueName(name string) string {
+	var out = name
+	for i := 2; ; i++ {
+		if _, exists := g.names[out]; !exists {
+			break
+		}
+		out = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[out] = struct{}{}
+	return out
+}
+
+// goLeafType selects a type for a param without children based on how it was used
+func goLeafType(param *Param) string {
+	var usageTypes = make(map[UsageType]bool)
+	for _, usage := range param.Usage {
+		usageTypes[usage.Type] = true
+	}
+	switch {
+	case usageTypes[UsageUnknown]:
+		return "interface{}"
+	case usageTypes[UsageFull]:
+		return "string"
+	case usageTypes[UsageMeta]:
+		return "[]interface{}"
+	case usageTypes[UsageExists]:
+		return "bool"
+	}
+	return "interface{}"
+}
+
+// mergeChildren combines all the children of a param, which is used
+// to describe the values of a map accessed via a map index.
+func mergeChildren(param *Param) *Param {
+	out := newParam()
+	for _, child := range param.Children {
+		out.merge(child)
+	}
+	return out
+}
+
+// goFieldName converts a field name into an exported Go identifier that
+// data.New will convert back to the original name.
+// An empty string is returned if this is not possible.
+func goFieldName(name string) string {
+	var firstRune, size = utf8.DecodeRuneInString(name)
+	if firstRune == utf8.RuneError || !unicode.IsLower(firstRune) && !unicode.IsUpper(firstRune) {
+		return ""
+	}
+	out := string(unicode.ToUpper(firstRune)) + name[size:]
+	if !token.IsIdentifier(out) || string(unicode.ToLower(firstRune))+name[size:] != name {
+		return ""
+	}
+	return out
+}
+
+// usageTemplates lists the templates that use this param or its descendants
+func usageTemplates(param *Param) []string {
+	var set = make(map[string]struct{})
+	for _, usage := range param.leafUsage() {
+		set[usage.Template] = struct{}{}
+	}
+	var out []string
+	for template := range set {
+		out = append(out, template)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func sortedNames(params Params) []Identifier {
+	var out []Identifier
+	for name := range params {
+		out = append(out, name)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].String() < out[j].String()
+	})
+	return out
+}
@@ -0,0 +1,82 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestGenerateGoStructs(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param entity
+	* @param key
+	*/
+	{template .main}
+		{$entity.name}
+		{if $entity.closed}
+			Closed
+		{/if}
+		{foreach $phone in $entity.phones}
+			{$phone.number}
+		{/foreach}
+		{call .hours}
+			{param hours: $entity.hours[$key] /}
+		{/call}
+	{/template}
+
+	/**
+	* @param hours
+	*/
+	{template .hours}
+		{$hours.open}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := soyusage.GenerateGoStructs("views", "Page", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, `// Code generated by soyusage. DO NOT EDIT.
+
+package views
+
+type Page struct {
+	// Entity is used by test.hours, test.main.
+	Entity *PageEntity
+	// Key is used by test.main.
+	Key string
+}
+
+type PageEntity struct {
+	// Closed is used by test.main.
+	Closed bool
+	// Hours is used by test.hours, test.main.
+	Hours map[string]*PageEntityHoursValue
+	// Name is used by test.main.
+	Name string
+	// Phones is used by test.main.
+	Phones []*PageEntityPhones
+}
+
+type PageEntityHoursValue struct {
+	// Open is used by test.hours.
+	Open string
+}
+
+type PageEntityPhones struct {
+	// Number is used by test.main.
+	Number string
+}
+`, string(got))
+}
@@ -0,0 +1,81 @@
+package soyusage
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var graphQLName = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+// GraphQLSelection generates a GraphQL selection set requesting the data used by a template.
+//
+// The roots map the names of template params to the root field to be queried for them,
+// including any arguments, such as "location(id: $id)". Root fields with a different
+// name to the param are aliased so the response can be passed directly to the template.
+// Params without a root are omitted.
+//
+// Fields without children are selected as scalars. An error is returned if a field with
+// children is used in its entirety (UsageFull or UsageUnknown) or accessed via a map index,
+// as GraphQL has no way to select all fields of an object. Fields without children are
+// also assumed to be objects if they are printed with the json directive, or are root
+// fields used in their entirety.
+func GraphQLSelection(params Params, roots map[string]string) (string, error) {
+	var out strings.Builder
+	out.WriteString("{\n")
+	for _, name := range sortedNames(params) {
+		root, hasRoot := roots[name.String()]
+		if !hasRoot {
+			continue
+		}
+		field := strings.TrimSpace(root)
+		if fieldName := strings.TrimSpace(strings.SplitN(field, "(", 2)[0]); fieldName != name.String() {
+			field = name.String() + ": " + field
+		}
+		if err := writeGraphQLField(&out, 1, Path{name}, field, params[name]); err != nil {
+			return "", err
+		}
+	}
+	out.WriteString("}\n")
+	return out.String(), nil
+}
+
+func writeGraphQLField(out *strings.Builder, depth int, path Path, field string, param *Param) error {
+	indent := strings.Repeat("  ", depth)
+	entire := param.entireUsage()
+	if len(entire) > 0 && (len(param.Children) > 0 || depth == 1 || printedAsJSON(entire)) {
+		return fmt.Errorf("%v is used in its entirety, which would require selecting all of its fields", path)
+	}
+	if len(param.Children) == 0 {
+		fmt.Fprintf(out, "%s%s\n", indent, field)
+		return nil
+	}
+	if hasMapIndex(param.Children) {
+		return fmt.Errorf("%v is accessed with a non-constant key, which cannot be selected", path)
+	}
+
+	fmt.Fprintf(out, "%s%s {\n", indent, field)
+	for _, name := range sortedNames(param.Children) {
+		childPath := path.append(name)
+		if !graphQLName.MatchString(name.String()) {
+			return fmt.Errorf("%v is not a valid GraphQL field name", childPath)
+		}
+		if err := writeGraphQLField(out, depth+1, childPath, name.String(), param.Children[name]); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(out, "%s}\n", indent)
+	return nil
+}
+
+// printedAsJSON returns true if any of the usages print a value with the json directive
+func printedAsJSON(usages []Usage) bool {
+	for _, usage := range usages {
+		for _, directive := range usage.directives {
+			if directive.Name == "json" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,218 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestGraphQLSelection(t *testing.T) {
+	var tests = []struct {
+		name        string
+		templates   map[string]string
+		roots       map[string]string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name: "fields are selected",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				* @param locale
+				*/
+				{template .main}
+					{$entity.name}
+					{if $entity.closed}
+						Closed
+					{/if}
+					{foreach $phone in $entity.phones}
+						{$phone.number}
+					{/foreach}
+					{$locale}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "location(id: $id)",
+			},
+			expected: `{
+  entity: location(id: $id) {
+    closed
+    name
+    phones {
+      number
+    }
+  }
+}
+`,
+		},
+		{
+			name: "root fields matching the param are not aliased",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				*/
+				{template .main}
+					{$entity.name}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "entity(id: 1)",
+			},
+			expected: `{
+  entity(id: 1) {
+    name
+  }
+}
+`,
+		},
+		{
+			name: "objects used in their entirety fail",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				*/
+				{template .main}
+					{myFunc($entity.address)}
+					{$entity.address.city}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "entity",
+			},
+			expectedErr: "entity.address is used in its entirety, which would require selecting all of its fields",
+		},
+		{
+			name: "objects used in their entirety before their fields fail",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				*/
+				{template .main}
+					{$entity|json}
+					{$entity.name}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "entity",
+			},
+			expectedErr: "entity is used in its entirety, which would require selecting all of its fields",
+		},
+		{
+			name: "objects used in their entirety after their fields fail",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				*/
+				{template .main}
+					{$entity.name}
+					{$entity|json}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "entity",
+			},
+			expectedErr: "entity is used in its entirety, which would require selecting all of its fields",
+		},
+		{
+			name: "root fields passed to functions fail",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				*/
+				{template .main}
+					{myFunc($entity)}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "entity",
+			},
+			expectedErr: "entity is used in its entirety, which would require selecting all of its fields",
+		},
+		{
+			name: "fields printed as json fail",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				*/
+				{template .main}
+					{$entity.name}
+					{$entity.hours|json}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "entity",
+			},
+			expectedErr: "entity.hours is used in its entirety, which would require selecting all of its fields",
+		},
+		{
+			name: "map index fails",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param entity
+				* @param key
+				*/
+				{template .main}
+					{$entity.fields[$key]}
+				{/template}
+			`,
+			},
+			roots: map[string]string{
+				"entity": "entity",
+			},
+			expectedErr: "entity.fields is accessed with a non-constant key, which cannot be selected",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate("test.main", registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := soyusage.GraphQLSelection(params, test.roots)
+			if test.expectedErr != "" {
+				if err == nil || err.Error() != test.expectedErr {
+					t.Fatalf("expected error %q, got %v", test.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			must.BeEqual(t, test.expected, got)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package soyusage
+
+import "github.com/yext/soy/ast"
+
+// Guard describes a condition that must hold for a usage to occur, such as the
+// condition of an if statement containing the usage.
+type Guard struct {
+	// Node is the condition of an if statement or ternary, the first argument of
+	// and or or, or the value of a switch statement.
+	Node ast.Node
+	// Values lists the case values for a guard from a switch statement. The value
+	// of the switch must equal one of these values.
+	// For the default case, this lists the values of all cases.
+	Values []ast.Node
+	// Negated is set if the condition must be false, as for an if statement with an
+	// earlier branch or the second argument of or, or the value must not equal any of
+	// the case values.
+	Negated bool
+
+	// refs maps the data refs in Node and Values to the params they refer to,
+	// if they could be identified without any non-constant access.
+	refs map[*ast.DataRefNode][]*Param
+}
+
+// isSwitch returns true if this guard comes from a switch statement
+func (g Guard) isSwitch() bool {
+	return g.Values != nil
+}
+
+func (g Guard) equal(other Guard) bool {
+	if g.Node != other.Node || g.Negated != other.Negated || len(g.Values) != len(other.Values) {
+		return false
+	}
+	for i := range g.Values {
+		if g.Values[i] != other.Values[i] {
+			return false
+		}
+	}
+	if len(g.refs) != len(other.refs) {
+		return false
+	}
+	for node, params := range g.refs {
+		otherParams := other.refs[node]
+		if len(params) != len(otherParams) {
+			return false
+		}
+		for i := range params {
+			if params[i] != otherParams[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sameGuards(a, b []Guard) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Guards lists the conditions that must hold for this usage to occur, from the
+// outermost to the innermost.
+func (u Usage) Guards() []Guard {
+	return u.guards
+}
+
+// guarded creates a new scope "inside" the current scope, in which usages
+// are subject to an additional guard.
+func guarded(s *scope, node ast.Node, values []ast.Node, negated bool) *scope {
+	guard := Guard{
+		Node:    node,
+		Values:  values,
+		Negated: negated,
+		refs:    make(map[*ast.DataRefNode][]*Param),
+	}
+	resolveGuardRefs(s, guard.refs, node)
+	for _, value := range values {
+		resolveGuardRefs(s, guard.refs, value)
+	}
+
+	cs := s.inner()
+	cs.guards = append(append([]Guard(nil), s.guards...), guard)
+	return cs
+}
+
+// resolveGuardRefs finds the params for each data ref within a node
+func resolveGuardRefs(s *scope, refs map[*ast.DataRefNode][]*Param, node ast.Node) {
+	if dataRef, isDataRef := node.(*ast.DataRefNode); isDataRef {
+		if params := resolveDataRef(s, dataRef); len(params) > 0 {
+			refs[dataRef] = params
+		}
+		return
+	}
+	if parent, isParent := node.(ast.ParentNode); isParent {
+		for _, child := range parent.Children() {
+			resolveGuardRefs(s, refs, child)
+		}
+	}
+}
+
+// resolveDataRef returns the params referenced by a data ref without recording any usage.
+// Nil is returned if the data ref has not been recorded, or includes non-constant access.
+func resolveDataRef(s *scope, node *ast.DataRefNode) []*Param {
+	params, exists := s.variables[Name(node.Key)]
+	if !exists {
+		param, isParam := s.parameters[Name(node.Key)]
+		if !isParam {
+			return nil
+		}
+		params = []*Param{param}
+	}
+	// Non-constant placeholders add no information about the referenced value
+	var resolved []*Param
+	for _, param := range params {
+		if _, isNonConstant := param.constant.(nonConstant); !isNonConstant {
+			resolved = append(resolved, param)
+		}
+	}
+	params = resolved
+	for _, access := range node.Access {
+		var next []*Param
+		for _, param := range params {
+			if param.isConstant() {
+				return nil
+			}
+			switch access := access.(type) {
+			case *ast.DataRefKeyNode:
+				child, exists := param.Children[Name(access.Key)]
+				if !exists {
+					return nil
+				}
+				next = append(next, child)
+			case *ast.DataRefIndexNode:
+				next = append(next, param)
+			default:
+				return nil
+			}
+		}
+		params = next
+	}
+	return params
+}
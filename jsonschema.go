@@ -0,0 +1,318 @@
+package soyusage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// JSONSchema is the subset of JSON Schema needed to validate parameter usage.
+// Keywords that are not listed here are ignored.
+type JSONSchema struct {
+	Type                 JSONSchemaType         `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Definitions          map[string]*JSONSchema `json:"definitions,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+
+	// disallow is set for the boolean schema false, which matches no values
+	disallow bool
+}
+
+// JSONSchemaType lists the types permitted by a schema.
+// An empty list permits any type.
+type JSONSchemaType []string
+
+// UnmarshalJSON accepts a type specified as either a single string or a list of strings
+func (t *JSONSchemaType) UnmarshalJSON(content []byte) error {
+	var single string
+	if err := json.Unmarshal(content, &single); err == nil {
+		*t = JSONSchemaType{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(content, &list); err != nil {
+		return fmt.Errorf("type must be a string or list of strings: %v", err)
+	}
+	*t = list
+	return nil
+}
+
+// UnmarshalJSON accepts boolean schemas in addition to schema objects
+func (s *JSONSchema) UnmarshalJSON(content []byte) error {
+	content = bytes.TrimSpace(content)
+	switch string(content) {
+	case "true":
+		*s = JSONSchema{}
+		return nil
+	case "false":
+		*s = JSONSchema{disallow: true}
+		return nil
+	}
+	type schema JSONSchema
+	return json.Unmarshal(content, (*schema)(s))
+}
+
+// LoadJSONSchema reads a JSON Schema from the specified file
+func LoadJSONSchema(filename string) (*JSONSchema, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var schema JSONSchema
+	if err := json.Unmarshal(content, &schema); err != nil {
+		return nil, fmt.Errorf("reading schema %s: %v", filename, err)
+	}
+	return &schema, nil
+}
+
+func (t JSONSchemaType) allows(name string) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, allowed := range t {
+		if allowed == name || (name == "number" && allowed == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// isOnly returns true if the type permits values of the named type and no others,
+// ignoring null.
+func (t JSONSchemaType) isOnly(name string) bool {
+	if len(t) == 0 {
+		return false
+	}
+	for _, allowed := range t {
+		if allowed != name && allowed != "null" {
+			return false
+		}
+	}
+	return true
+}
+
+func (t JSONSchemaType) isScalar() bool {
+	return len(t) > 0 && !t.allows("object") && !t.allows("array")
+}
+
+func (t JSONSchemaType) String() string {
+	return strings.Join(t, "|")
+}
+
+// CheckAgainstJSONSchema validates the usage of template params against a JSON Schema
+// for each param. Params without a schema are not checked.
+//
+// Diagnostics are reported for fields that the schema does not allow, for arrays of
+// scalar values accessed as maps, for objects iterated as lists, for fields the
+// schema marks as optional that are read outside of a check that they, or a value
+// containing them, exist and for fields compared against values that are not in their enum.
+func CheckAgainstJSONSchema(params Params, schemas map[string]*JSONSchema) []Diagnostic {
+	var diagnostics []Diagnostic
+	for name, schema := range schemas {
+		param, used := params[Name(name)]
+		if !used {
+			continue
+		}
+		c := &schemaChecker{root: schema}
+		c.check(Path{Name(name)}, param, schema)
+		diagnostics = append(diagnostics, c.diagnostics...)
+	}
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+type schemaChecker struct {
+	root        *JSONSchema
+	diagnostics []Diagnostic
+}
+
+func (c *schemaChecker) report(path Path, param *Param, message string, args ...interface{}) {
+	c.diagnostics = append(c.diagnostics, newDiagnostic(path, param, message, args...))
+}
+
+func (c *schemaChecker) check(path Path, param *Param, schema *JSONSchema) {
+	schema = c.resolve(schema)
+	if schema == nil {
+		return
+	}
+	if schema.disallow {
+		c.report(path, param, "field is not allowed by the schema")
+		return
+	}
+	if param.iterated && schema.Type.isOnly("object") {
+		c.report(path, param, "field is an object in the schema but is iterated as a list")
+	}
+	if len(schema.Enum) > 0 {
+		c.checkCompared(path, param, schema)
+	}
+	c.checkValue(path, param, schema)
+}
+
+// checkValue validates the fields accessed on a param, or the items of a list param
+func (c *schemaChecker) checkValue(path Path, param *Param, schema *JSONSchema) {
+	schema = c.resolve(schema)
+	if schema == nil || len(param.Children) == 0 {
+		return
+	}
+	if schema.disallow {
+		c.report(path, param, "field is not allowed by the schema")
+		return
+	}
+	if schema.Type.isScalar() {
+		c.report(path, param, "field is of type %v and has no fields", schema.Type)
+		return
+	}
+	if schema.Type.isOnly("array") {
+		items := c.resolve(schema.Items)
+		if items != nil && items.Type.isScalar() {
+			c.report(path, param, "field is an array of %v and cannot be accessed as a map", items.Type)
+			return
+		}
+		// Fields of a list are the fields of its items
+		c.checkValue(path, param, items)
+		return
+	}
+
+	var required = make(map[string]struct{})
+	for _, name := range schema.Required {
+		required[name] = struct{}{}
+	}
+	for name, child := range param.Children {
+		childPath := path.append(name)
+		if _, isMapIndex := name.(MapIndex); isMapIndex {
+			if schema.AdditionalProperties != nil {
+				c.check(childPath, child, schema.AdditionalProperties)
+			}
+			continue
+		}
+		property, isProperty := schema.Properties[name.String()]
+		if !isProperty {
+			if schema.AdditionalProperties == nil {
+				continue
+			}
+			if additional := c.resolve(schema.AdditionalProperties); additional != nil && additional.disallow {
+				c.report(childPath, child, "field is not allowed by the schema%s", didYouMean(name.String(), propertyNames(schema)))
+				continue
+			}
+			c.check(childPath, child, schema.AdditionalProperties)
+			continue
+		}
+		if _, isRequired := required[name.String()]; !isRequired && child.readUnguarded() {
+			c.report(childPath, child, "field is optional in the schema but is read without checking that it exists")
+		}
+		c.check(childPath, child, property)
+	}
+}
+
+// readUnguarded returns true if any usage reads this param, or accesses its fields,
+// outside of a guard ensuring that it exists.
+func (p *Param) readUnguarded() bool {
+	for _, usage := range p.leafUsage() {
+		if usage.Type != UsageExists && !usage.guardedAccess(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// guardedAccess returns true if a usage does not access a param, or is within a guard
+// ensuring that the param or a value containing it exists.
+// Usages recorded for an ancestor are added to the leaves of the param tree, but
+// reading the ancestor does not access its optional fields.
+func (u Usage) guardedAccess(param *Param) bool {
+	if u.chain == nil {
+		return ensuredNonNull(u.guards, param)
+	}
+	for _, params := range u.chain {
+		var accessed bool
+		for _, p := range params {
+			if ensuredNonNull(u.guards, p) {
+				return true
+			}
+			accessed = accessed || p == param
+		}
+		if accessed {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCompared validates the values a param is compared against using the enum of its schema
+func (c *schemaChecker) checkCompared(path Path, param *Param, schema *JSONSchema) {
+	var allowed []string
+	for _, value := range schema.Enum {
+		if str, isString := value.(string); isString {
+			allowed = append(allowed, str)
+		}
+	}
+	for _, value := range param.ComparedValues() {
+		if !inEnum(value, schema.Enum) {
+			str, isString := value.(string)
+			if !isString {
+				c.report(path, param, "field is compared with %v, which is not one of its allowed values", value)
+				continue
+			}
+			c.report(path, param, "field is compared with %q, which is not one of its allowed values%s", str, didYouMean(str, allowed))
+		}
+	}
+}
+
+// inEnum returns true if a constant value from a template is listed in a decoded JSON enum
+func inEnum(value interface{}, enum []interface{}) bool {
+	for _, allowed := range enum {
+		switch v := value.(type) {
+		case int:
+			if number, isNumber := allowed.(float64); isNumber && number == float64(v) {
+				return true
+			}
+		default:
+			if allowed == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve follows any local references in the schema
+func (c *schemaChecker) resolve(schema *JSONSchema) *JSONSchema {
+	for depth := 0; schema != nil && schema.Ref != ""; depth++ {
+		if depth > 32 {
+			return nil
+		}
+		var (
+			definitions map[string]*JSONSchema
+			name        string
+		)
+		switch {
+		case strings.HasPrefix(schema.Ref, "#/definitions/"):
+			definitions, name = c.root.Definitions, strings.TrimPrefix(schema.Ref, "#/definitions/")
+		case strings.HasPrefix(schema.Ref, "#/$defs/"):
+			definitions, name = c.root.Defs, strings.TrimPrefix(schema.Ref, "#/$defs/")
+		case schema.Ref == "#":
+			schema = c.root
+			continue
+		default:
+			// References to other documents cannot be checked
+			return nil
+		}
+		schema = definitions[name]
+	}
+	return schema
+}
+
+func propertyNames(schema *JSONSchema) []string {
+	var out []string
+	for name := range schema.Properties {
+		out = append(out, name)
+	}
+	return out
+}
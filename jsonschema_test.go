@@ -0,0 +1,274 @@
+package soyusage_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestCheckAgainstJSONSchema(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		schemas      map[string]string
+		expected     []string
+	}{
+		{
+			name: "valid usage has no diagnostics",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.name}
+					{if $loc.address}
+						{$loc.address.city}
+					{/if}
+					{foreach $phone in $loc.phones}
+						{$phone.number}
+					{/foreach}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			schemas: map[string]string{
+				"loc": `{
+					"type": "object",
+					"required": ["name", "phones"],
+					"properties": {
+						"name": {"type": "string"},
+						"address": {"$ref": "#/definitions/address"},
+						"phones": {"type": "array", "items": {"type": "object", "required": ["number"], "properties": {"number": {"type": "string"}}}}
+					},
+					"definitions": {
+						"address": {"type": "object", "properties": {"city": {"type": "string"}}}
+					}
+				}`,
+			},
+		},
+		{
+			name: "unknown fields are reported",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.adress}
+					{$loc.extra.value}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			schemas: map[string]string{
+				"loc": `{
+					"type": "object",
+					"required": ["address"],
+					"properties": {
+						"address": {"type": "string"}
+					},
+					"additionalProperties": false
+				}`,
+			},
+			expected: []string{
+				`loc.adress: field is not allowed by the schema, did you mean "address"?`,
+				`loc.extra: field is not allowed by the schema`,
+			},
+		},
+		{
+			name: "lists and maps are distinguished",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.tags.first}
+					{foreach $hour in $loc.hours}
+						{$hour}
+					{/foreach}
+					{$loc.name.first}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			schemas: map[string]string{
+				"loc": `{
+					"type": "object",
+					"required": ["tags", "hours", "name"],
+					"properties": {
+						"tags": {"type": "array", "items": {"type": "string"}},
+						"hours": {"type": ["object", "null"]},
+						"name": {"type": "string"}
+					}
+				}`,
+			},
+			expected: []string{
+				`loc.hours: field is an object in the schema but is iterated as a list`,
+				`loc.name: field is of type string and has no fields`,
+				`loc.tags: field is an array of string and cannot be accessed as a map`,
+			},
+		},
+		{
+			name: "optional fields must be guarded",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.description}
+					{if $loc.website}
+						{$loc.website}
+					{/if}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			schemas: map[string]string{
+				"loc": `{
+					"type": "object",
+					"properties": {
+						"description": {"type": "string"},
+						"website": {"type": "string"}
+					}
+				}`,
+			},
+			expected: []string{
+				`loc.description: field is optional in the schema but is read without checking that it exists`,
+			},
+		},
+		{
+			name: "existence checks after a read do not guard it",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.hours.today}
+					{if $loc.hours}Has hours{/if}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			schemas: map[string]string{
+				"loc": `{
+					"type": "object",
+					"properties": {
+						"hours": {"type": "object", "required": ["today"], "properties": {"today": {"type": "string"}}}
+					}
+				}`,
+			},
+			expected: []string{
+				`loc.hours: field is optional in the schema but is read without checking that it exists`,
+			},
+		},
+		{
+			name: "reads outside of an existence check are reported",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{if $loc.hours}Has hours{/if}
+					{$loc.hours.today}
+					{if $loc.website}
+						{$loc.website}
+					{/if}
+					{$loc.name}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			schemas: map[string]string{
+				"loc": `{
+					"type": "object",
+					"properties": {
+						"hours": {"type": "object", "required": ["today"], "properties": {"today": {"type": "string"}}},
+						"website": {"type": "string"},
+						"name": {"type": "string"}
+					}
+				}`,
+			},
+			expected: []string{
+				`loc.hours: field is optional in the schema but is read without checking that it exists`,
+				`loc.name: field is optional in the schema but is read without checking that it exists`,
+			},
+		},
+		{
+			name: "compared values must be in the enum",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{if $loc.status == 'OPEN'}Open{/if}
+					{if $loc.status != 'CLOSD'}Not closed{/if}
+					{switch $loc.priority}
+						{case 1, 4}High
+					{/switch}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			schemas: map[string]string{
+				"loc": `{
+					"type": "object",
+					"required": ["status", "priority"],
+					"properties": {
+						"status": {"type": "string", "enum": ["OPEN", "CLOSED"]},
+						"priority": {"type": "integer", "enum": [1, 2, 3]}
+					}
+				}`,
+			},
+			expected: []string{
+				`loc.priority: field is compared with 4, which is not one of its allowed values`,
+				`loc.status: field is compared with "CLOSD", which is not one of its allowed values, did you mean "CLOSED"?`,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var schemas = make(map[string]*soyusage.JSONSchema)
+			for name, content := range test.schemas {
+				var schema soyusage.JSONSchema
+				if err := json.Unmarshal([]byte(content), &schema); err != nil {
+					t.Fatal(err)
+				}
+				schemas[name] = &schema
+			}
+			var got []string
+			for _, diagnostic := range soyusage.CheckAgainstJSONSchema(params, schemas) {
+				got = append(got, diagnostic.String())
+			}
+			must.BeEqual(t, test.expected, got)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package soyusage
+
+import (
+	"sort"
+
+	"github.com/yext/soy/data"
+)
+
+// MissingField describes a value read by a template that is absent from the input data.
+type MissingField struct {
+	// Path identifies the missing value.
+	// Values within lists are identified by the path of the list.
+	Path Path
+	// Usage lists the usages of the missing value or its descendants that read its value.
+	Usage []Usage
+	// Optional is set if the value is declared as an optional param, or is checked
+	// for existence by the templates, so its absence may be expected.
+	Optional bool
+}
+
+// Missing lists the values read by templates according to the provided usage analysis
+// that are absent or null in the input data. It performs the inverse of Extract.
+//
+// Only the highest missing value on each path is reported. Values that are only
+// checked for existence are not considered to be read, and values accessed via
+// non-constant map keys are only checked for the keys that are present.
+// The results are sorted by path.
+func Missing(in data.Value, params Params) []MissingField {
+	var m = make(missingFields)
+	m.check(nil, in, params, false)
+
+	var out []MissingField
+	for _, field := range m {
+		out = append(out, *field)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Path.String() < out[j].Path.String()
+	})
+	return out
+}
+
+// missingFields collects missing fields by path, combining those found in list elements
+type missingFields map[string]*MissingField
+
+// check records missing fields for each of the provided params, within the input value
+func (m missingFields) check(path Path, in data.Value, params Params, checked bool) {
+	inMap, isMap := in.(data.Map)
+	for name, param := range params {
+		if _, isMapIndex := name.(MapIndex); isMapIndex {
+			for key, value := range inMap {
+				if _, isNamed := params[Name(key)]; !isNamed {
+					m.checkParam(path.append(name), value, param, checked)
+				}
+			}
+			continue
+		}
+		var value data.Value
+		if isMap {
+			value = inMap[name.String()]
+		}
+		m.checkParam(path.append(name), value, param, checked)
+	}
+}
+
+// checkParam records missing fields for a single param, given its value
+func (m missingFields) checkParam(path Path, in data.Value, param *Param, checked bool) {
+	checked = checked || param.optional || param.hasUsage(UsageExists)
+	switch v := in.(type) {
+	case nil, data.Null, data.Undefined:
+		if param.isRead() {
+			m.add(path, param, checked)
+		}
+		return
+	case data.List:
+		for _, item := range v {
+			m.checkParam(path, item, param, checked)
+		}
+		return
+	}
+	m.check(path, in, param.Children, checked)
+}
+
+func (m missingFields) add(path Path, param *Param, checked bool) {
+	key := path.String()
+	if _, exists := m[key]; exists {
+		return
+	}
+	field := &MissingField{
+		Path:     path,
+		Optional: checked,
+	}
+	for _, usage := range param.leafUsage() {
+		if usage.Type == UsageExists {
+			field.Optional = true
+			continue
+		}
+		field.Usage = append(field.Usage, usage)
+	}
+	m[key] = field
+}
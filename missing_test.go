@@ -0,0 +1,187 @@
+package soyusage_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func TestMissing(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		in           data.Value
+		expected     []string
+	}{
+		{
+			name: "complete data has no missing fields",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.name}
+					{foreach $phone in $loc.phones}
+						{$phone.number}
+					{/foreach}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"name": "name",
+					"phones": []interface{}{
+						map[string]interface{}{"number": "1"},
+					},
+				},
+			}),
+		},
+		{
+			name: "reports highest missing value",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				* @param site
+				*/
+				{template .main}
+					{$loc.name}
+					{$loc.address.city}
+					{$loc.address.region}
+					{$site.domain}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"name": nil,
+				},
+			}),
+			expected: []string{
+				"loc.address (2 usages)",
+				"loc.name (1 usages)",
+				"site (1 usages)",
+			},
+		},
+		{
+			name: "combines list elements",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{foreach $phone in $loc.phones}
+						{$phone.number}
+					{/foreach}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{
+					"phones": []interface{}{
+						map[string]interface{}{},
+						map[string]interface{}{"number": "1"},
+						map[string]interface{}{},
+					},
+				},
+			}),
+			expected: []string{
+				"loc.phones.number (1 usages)",
+			},
+		},
+		{
+			name: "marks optional and checked values",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				* @param? site
+				*/
+				{template .main}
+					{if $loc.description}
+						{$loc.description}
+					{/if}
+					{if $loc.hours}Has hours{/if}
+					{$site.domain}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"loc": map[string]interface{}{},
+			}),
+			expected: []string{
+				"loc.description (1 usages, optional)",
+				"site (1 usages, optional)",
+			},
+		},
+		{
+			name: "checks keys present for non-constant map access",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param hours
+				* @param day
+				*/
+				{template .main}
+					{$hours[$day].open}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			in: data.New(map[string]interface{}{
+				"day": "mon",
+				"hours": map[string]interface{}{
+					"mon": map[string]interface{}{"open": "9"},
+					"tue": map[string]interface{}{},
+				},
+			}),
+			expected: []string{
+				"hours[?].open (1 usages)",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, field := range soyusage.Missing(test.in, params) {
+				description := fmt.Sprintf("%v (%d usages", field.Path, len(field.Usage))
+				if field.Optional {
+					description += ", optional"
+				}
+				got = append(got, description+")")
+				for _, usage := range field.Usage {
+					must.BeEqual(t, test.templateName, usage.Template)
+				}
+			}
+			must.BeEqual(t, test.expected, got)
+		})
+	}
+}
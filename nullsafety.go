@@ -0,0 +1,270 @@
+package soyusage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/yext/soy/ast"
+)
+
+// CheckNullSafety reports data refs that access the fields of a value that may be null,
+// without checking that the value exists or using null-safe access. Such accesses fail
+// when rendering if the value is null or missing.
+//
+// Values may be null if they are declared as optional params, or if the templates check
+// whether they exist, such as with {if $a}, $a ?: b, $a == null or $a?.b.
+// An access is considered safe if it occurs within an if statement, switch case, ternary,
+// and or or that ensures the value is not null.
+// One diagnostic is returned for each access, identified by the path of the value that
+// may be null. The results are sorted by path.
+func CheckNullSafety(params Params) []Diagnostic {
+	var c = nullSafetyChecker{
+		paths:    make(map[*Param]Path),
+		reported: make(map[nullSafetyKey]struct{}),
+	}
+	for name, param := range params {
+		c.findPaths(Path{name}, param)
+	}
+	visited := make(map[*Param]struct{})
+	for _, param := range params {
+		c.check(param, visited)
+	}
+	sort.SliceStable(c.out, func(i, j int) bool {
+		if c.out[i].Path.String() != c.out[j].Path.String() {
+			return c.out[i].Path.String() < c.out[j].Path.String()
+		}
+		return c.out[i].Message < c.out[j].Message
+	})
+	return c.out
+}
+
+// nullSafetyKey identifies an access within a data ref
+type nullSafetyKey struct {
+	template string
+	node     ast.Node
+	access   int
+}
+
+type nullSafetyChecker struct {
+	// paths holds the path to each param in the analysis
+	paths    map[*Param]Path
+	reported map[nullSafetyKey]struct{}
+	out      []Diagnostic
+}
+
+func (c *nullSafetyChecker) findPaths(path Path, param *Param) {
+	if _, found := c.paths[param]; found {
+		return
+	}
+	c.paths[param] = path
+	for name, child := range param.Children {
+		c.findPaths(path.append(name), child)
+	}
+}
+
+// check reports unsafe accesses in the usages of a param and its descendants
+func (c *nullSafetyChecker) check(param *Param, visited map[*Param]struct{}) {
+	if _, seen := visited[param]; seen {
+		return
+	}
+	visited[param] = struct{}{}
+	for _, usage := range param.Usage {
+		c.checkUsage(usage)
+	}
+	for _, child := range param.Children {
+		c.check(child, visited)
+	}
+}
+
+func (c *nullSafetyChecker) checkUsage(usage Usage) {
+	dataRef, isDataRef := usage.node.(*ast.DataRefNode)
+	if !isDataRef || len(usage.chain) != len(dataRef.Access)+1 {
+		return
+	}
+	for i, access := range dataRef.Access {
+		// Accesses following a null-safe access are skipped if the value is null
+		if isNullSafe(access) {
+			return
+		}
+		for _, param := range usage.chain[i] {
+			path, inAnalysis := c.paths[param]
+			if !inAnalysis || !param.mayBeNull() || ensuredNonNull(usage.guards, param) {
+				continue
+			}
+			key := nullSafetyKey{template: usage.Template, node: dataRef, access: i}
+			if _, reported := c.reported[key]; reported {
+				continue
+			}
+			c.reported[key] = struct{}{}
+
+			reason := "may be null"
+			if param.optional {
+				reason = "is an optional param"
+			}
+			value := &ast.DataRefNode{Pos: dataRef.Pos, Key: dataRef.Key, Access: dataRef.Access[:i]}
+			c.out = append(c.out, Diagnostic{
+				Path: path,
+				Message: fmt.Sprintf(
+					"%s accesses a field of %s, which %s; check it with {if %s} or use %s",
+					dataRef,
+					value,
+					reason,
+					value,
+					nullSafeDataRef(dataRef, i),
+				),
+				Usage: []Usage{usage},
+			})
+		}
+	}
+}
+
+// mayBeNull returns true if the value of this param may be null, as it was declared as
+// optional or its existence was checked
+func (p *Param) mayBeNull() bool {
+	return p.optional || p.nullChecked
+}
+
+// nullSafeDataRef returns a copy of a data ref with the specified access made null-safe
+func nullSafeDataRef(dataRef *ast.DataRefNode, index int) *ast.DataRefNode {
+	var access = append([]ast.Node(nil), dataRef.Access...)
+	switch v := access[index].(type) {
+	case *ast.DataRefKeyNode:
+		nullSafe := *v
+		nullSafe.NullSafe = true
+		access[index] = &nullSafe
+	case *ast.DataRefIndexNode:
+		nullSafe := *v
+		nullSafe.NullSafe = true
+		access[index] = &nullSafe
+	case *ast.DataRefExprNode:
+		nullSafe := *v
+		nullSafe.NullSafe = true
+		access[index] = &nullSafe
+	}
+	return &ast.DataRefNode{Pos: dataRef.Pos, Key: dataRef.Key, Access: access}
+}
+
+// ensuredNonNull returns true if any of the provided guards ensures that a param is not null
+func ensuredNonNull(guards []Guard, param *Param) bool {
+	for _, guard := range guards {
+		if guard.ensuresNonNull(param) {
+			return true
+		}
+	}
+	return false
+}
+
+// ensuresNonNull returns true if a param cannot be null when this guard holds
+func (g Guard) ensuresNonNull(param *Param) bool {
+	// Evaluating the guard would fail if it accessed the fields of a null value
+	if g.dereferences(g.Node, param) {
+		return true
+	}
+	if g.isSwitch() {
+		return false
+	}
+	return g.implies(g.Node, param, !g.Negated)
+}
+
+// dereferences returns true if evaluating a node always accesses the fields of a param
+// without null-safe access.
+func (g Guard) dereferences(node ast.Node, param *Param) bool {
+	switch v := node.(type) {
+	case *ast.DataRefNode:
+		for _, access := range v.Access {
+			if isNullSafe(access) {
+				return false
+			}
+		}
+		for _, ref := range g.refs[v] {
+			if ref != param && isWithin(param, ref) {
+				return true
+			}
+		}
+		return false
+	case *ast.AndNode:
+		return g.dereferences(v.Arg1, param)
+	case *ast.OrNode:
+		return g.dereferences(v.Arg1, param)
+	case *ast.ElvisNode:
+		return g.dereferences(v.Arg1, param)
+	case *ast.TernNode:
+		return g.dereferences(v.Arg1, param)
+	case ast.ParentNode:
+		for _, child := range v.Children() {
+			if g.dereferences(child, param) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// implies returns true if a node having the specified truthiness implies
+// that a param is not null.
+func (g Guard) implies(node ast.Node, param *Param, truthy bool) bool {
+	switch v := node.(type) {
+	case *ast.DataRefNode:
+		return truthy && g.refersTo(v, param)
+	case *ast.NotNode:
+		return g.implies(v.Arg, param, !truthy)
+	case *ast.AndNode:
+		return truthy && (g.implies(v.Arg1, param, true) || g.implies(v.Arg2, param, true))
+	case *ast.OrNode:
+		return !truthy && (g.implies(v.Arg1, param, false) || g.implies(v.Arg2, param, false))
+	case *ast.EqNode:
+		return !truthy && g.comparesWithNull(v.Arg1, v.Arg2, param)
+	case *ast.NotEqNode:
+		return truthy && g.comparesWithNull(v.Arg1, v.Arg2, param)
+	case *ast.FunctionNode:
+		if v.Name == "isNonnull" && len(v.Args) == 1 {
+			dataRef, isDataRef := v.Args[0].(*ast.DataRefNode)
+			return truthy && isDataRef && g.refersTo(dataRef, param)
+		}
+	}
+	return false
+}
+
+// comparesWithNull returns true if one of the arguments of a comparison is null
+// and the other refers to a param
+func (g Guard) comparesWithNull(arg1, arg2 ast.Node, param *Param) bool {
+	if _, isNull := arg1.(*ast.NullNode); isNull {
+		arg1, arg2 = arg2, arg1
+	}
+	if _, isNull := arg2.(*ast.NullNode); !isNull {
+		return false
+	}
+	dataRef, isDataRef := arg1.(*ast.DataRefNode)
+	return isDataRef && g.refersTo(dataRef, param)
+}
+
+// refersTo returns true if a data ref refers to a param or one of its descendants,
+// so the param is not null if the data ref is not null
+func (g Guard) refersTo(dataRef *ast.DataRefNode, param *Param) bool {
+	for _, ref := range g.refs[dataRef] {
+		if ref == param || isWithin(param, ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithin returns true if a param is a descendant of an ancestor
+func isWithin(ancestor, param *Param) bool {
+	_, found := findParam(ancestor, param, maxGuardDepth, make(map[*Param]struct{}))
+	return found
+}
+
+// recordNullChecked records that the value of the params referenced by a node are checked
+// for null, such as when used as a condition.
+func recordNullChecked(s *scope, node ast.Node) {
+	dataRef, isDataRef := node.(*ast.DataRefNode)
+	if !isDataRef {
+		return
+	}
+	for _, param := range resolveDataRef(s, dataRef) {
+		if !param.isConstant() {
+			param.nullChecked = true
+		}
+	}
+}
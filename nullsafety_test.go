@@ -0,0 +1,176 @@
+package soyusage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestCheckNullSafety(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		expected     []string
+	}{
+		{
+			name: "guarded and null-safe access",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				* @param? site
+				*/
+				{template .main}
+					{$loc.name}
+					{if $site}
+						{$site.domain}
+					{/if}
+					{if $loc.address and $loc.address.city}
+						{$loc.address.city}
+					{/if}
+					{$loc.address ? $loc.address.region : ''}
+					{$loc.address?.country}
+					{if $loc.hours != null}
+						{$loc.hours.today}
+					{/if}
+					{if not $loc.logo}
+					{else}
+						{$loc.logo.url}
+					{/if}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+		},
+		{
+			name: "unguarded access to optional params",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param? site
+				*/
+				{template .main}
+					{$site.domain}
+					{$site.theme.color}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"site: $site.domain accesses a field of $site, which is an optional param; check it with {if $site} or use $site?.domain",
+				"site: $site.theme.color accesses a field of $site, which is an optional param; check it with {if $site} or use $site?.theme.color",
+			},
+		},
+		{
+			name: "unguarded access to values checked elsewhere",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{if $loc.address}
+						{$loc.address.line1}
+					{/if}
+					{$loc.address.city}
+					{$loc.hours?.today}
+					{$loc.hours.tomorrow.open}
+					{$loc.photo ?: 'none'}
+					{$loc.photo.url}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"loc.address: $loc.address.city accesses a field of $loc.address, which may be null; check it with {if $loc.address} or use $loc.address?.city",
+				"loc.hours: $loc.hours.tomorrow.open accesses a field of $loc.hours, which may be null; check it with {if $loc.hours} or use $loc.hours?.tomorrow.open",
+				"loc.photo: $loc.photo.url accesses a field of $loc.photo, which may be null; check it with {if $loc.photo} or use $loc.photo?.url",
+			},
+		},
+		{
+			name: "access in called templates",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{if $loc.address}
+						{call .address}
+							{param address: $loc.address /}
+						{/call}
+					{/if}
+					{call .address}
+						{param address: $loc.address /}
+					{/call}
+				{/template}
+
+				/**
+				* @param address
+				*/
+				{template .address}
+					{$address.city}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"loc.address: $address.city accesses a field of $address, which may be null; check it with {if $address} or use $address?.city",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, diagnostic := range soyusage.CheckNullSafety(params) {
+				got = append(got, diagnostic.String())
+				must.BeEqual(t, 1, len(diagnostic.Usage))
+			}
+			must.BeEqual(t, test.expected, got)
+		})
+	}
+}
+
+func TestCheckNullSafetyFormat(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `{namespace test}
+/**
+ * @param? site
+ */
+{template .main}
+{$site.domain}
+{/template}
+`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diagnostics := soyusage.CheckNullSafety(params)
+	must.BeEqual(t, 1, len(diagnostics))
+	if formatted := diagnostics[0].Format(registry); !strings.Contains(formatted, "test.soy, line 6") {
+		t.Errorf("expected usage position, got:\n%s", formatted)
+	}
+}
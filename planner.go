@@ -0,0 +1,201 @@
+package soyusage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/yext/soy/data"
+)
+
+// Resolver loads the value for a path in the template data.
+// The param describes the usage of the value and its descendants, which the
+// returned value must satisfy. Values that are not used may be omitted.
+type Resolver interface {
+	Resolve(ctx context.Context, param *Param) (data.Value, error)
+}
+
+// ResolverFunc allows a function to be used as a Resolver
+type ResolverFunc func(ctx context.Context, param *Param) (data.Value, error)
+
+// Resolve calls f(ctx, param)
+func (f ResolverFunc) Resolve(ctx context.Context, param *Param) (data.Value, error) {
+	return f(ctx, param)
+}
+
+// MemoryResolver returns a Resolver for a value held in memory.
+// Only the parts of the value that are used are returned, as for Extract.
+func MemoryResolver(value data.Value) Resolver {
+	return ResolverFunc(func(ctx context.Context, param *Param) (data.Value, error) {
+		return extractParam(param, value, emptyString), nil
+	})
+}
+
+// Planner loads template data using resolvers for each path, only invoking the
+// resolvers for paths that are used by templates.
+type Planner struct {
+	resolvers map[string]Resolver
+}
+
+// NewPlanner creates a Planner with no resolvers.
+func NewPlanner() *Planner {
+	return &Planner{
+		resolvers: make(map[string]Resolver),
+	}
+}
+
+// Register sets the resolver for a path, given as a sequence of field names separated
+// by "." such as "entity.reviews". The empty path registers a resolver for the root data.
+// Resolvers for longer paths provide values within those returned for shorter paths,
+// which must be maps: paths may not pass through lists.
+//
+// Register is not safe to call concurrently with other methods of the Planner, so all
+// resolvers should be registered before loading data.
+func (p *Planner) Register(prefix string, resolver Resolver) {
+	p.resolvers[prefix] = resolver
+}
+
+// Plan returns the paths of the resolvers that would be invoked to load data for
+// the provided usage analysis, sorted.
+func (p *Planner) Plan(params Params) []string {
+	var out []string
+	for _, call := range p.plan(params) {
+		out = append(out, call.path)
+	}
+	return out
+}
+
+// Load invokes the resolvers for each path used according to the provided usage analysis,
+// and combines their values. Resolvers are invoked concurrently. If any resolver returns
+// an error, the context passed to the other resolvers is cancelled and the first error
+// is returned.
+func (p *Planner) Load(ctx context.Context, params Params) (data.Map, error) {
+	calls := p.plan(params)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+		values   = make([]data.Value, len(calls))
+	)
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call resolverCall) {
+			defer wg.Done()
+			value, err := call.resolver.Resolve(ctx, call.param)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("resolving %q: %w", call.path, err)
+					cancel()
+				})
+				return
+			}
+			values[i] = value
+		}(i, call)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// Calls are sorted by path, so values for shorter paths are set first
+	var out = make(data.Map)
+	for i, call := range calls {
+		if values[i] == nil {
+			continue
+		}
+		if call.path == "" {
+			root, isMap := values[i].(data.Map)
+			if !isMap {
+				return nil, fmt.Errorf("resolving root: expected map, got %T", values[i])
+			}
+			out = root
+			continue
+		}
+		var err error
+		if out, err = setPath(out, strings.Split(call.path, "."), values[i]); err != nil {
+			return nil, fmt.Errorf("resolving %q: %w", call.path, err)
+		}
+	}
+	return out, nil
+}
+
+type resolverCall struct {
+	path     string
+	param    *Param
+	resolver Resolver
+}
+
+// plan lists the resolvers to invoke, sorted by path
+func (p *Planner) plan(params Params) []resolverCall {
+	var calls []resolverCall
+	if resolver, exists := p.resolvers[""]; exists {
+		calls = append(calls, resolverCall{param: &Param{Children: params}, resolver: resolver})
+	}
+	calls = p.planParams("", params, calls)
+	sort.Slice(calls, func(i, j int) bool {
+		return calls[i].path < calls[j].path
+	})
+	return calls
+}
+
+func (p *Planner) planParams(prefix string, params Params, calls []resolverCall) []resolverCall {
+	for name, param := range params {
+		if _, isName := name.(Name); !isName {
+			continue
+		}
+		path := name.String()
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		if resolver, exists := p.resolvers[path]; exists {
+			calls = append(calls, resolverCall{path: path, param: param, resolver: resolver})
+		}
+		if param.usedEntirely() {
+			calls = p.planEntire(path, param, calls)
+			continue
+		}
+		calls = p.planParams(path, param.Children, calls)
+	}
+	return calls
+}
+
+// planEntire lists the resolvers for every path within a param that is used entirely,
+// as all of the values they provide may be observed by templates.
+func (p *Planner) planEntire(prefix string, param *Param, calls []resolverCall) []resolverCall {
+	entire := &Param{Children: make(Params), Usage: param.entireUsage()}
+	for path, resolver := range p.resolvers {
+		if strings.HasPrefix(path, prefix+".") {
+			calls = append(calls, resolverCall{path: path, param: entire, resolver: resolver})
+		}
+	}
+	return calls
+}
+
+// setPath returns a copy of a map with the value at a path set.
+// Maps along the path are copied, so values returned by resolvers are not modified.
+// An error is returned if the path passes through a value that is not a map, such as a list.
+func setPath(in data.Map, names []string, value data.Value) (data.Map, error) {
+	var out = make(data.Map, len(in)+1)
+	for key, item := range in {
+		out[key] = item
+	}
+	if len(names) == 1 {
+		out[names[0]] = value
+		return out, nil
+	}
+	child, isMap := out[names[0]].(data.Map)
+	if !isMap && out[names[0]] != nil {
+		return nil, fmt.Errorf("cannot set %q within %T", strings.Join(names[1:], "."), out[names[0]])
+	}
+	child, err := setPath(child, names[1:], value)
+	if err != nil {
+		return nil, err
+	}
+	out[names[0]] = child
+	return out, nil
+}
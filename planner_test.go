@@ -0,0 +1,134 @@
+package soyusage_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func TestPlanner(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param entity
+	* @param site
+	*/
+	{template .main}
+		{$entity.name}
+		{foreach $review in $entity.reviews}
+			{$review.rating}
+		{/foreach}
+		{$site.domain}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu      sync.Mutex
+		invoked = make(map[string]int)
+	)
+	counted := func(path string, resolver soyusage.Resolver) soyusage.Resolver {
+		return soyusage.ResolverFunc(func(ctx context.Context, param *soyusage.Param) (data.Value, error) {
+			mu.Lock()
+			invoked[path]++
+			mu.Unlock()
+			return resolver.Resolve(ctx, param)
+		})
+	}
+
+	planner := soyusage.NewPlanner()
+	planner.Register("entity", counted("entity", soyusage.MemoryResolver(data.New(map[string]interface{}{
+		"name":        "Store",
+		"description": "unused",
+	}))))
+	planner.Register("entity.reviews", counted("entity.reviews", soyusage.MemoryResolver(data.New([]interface{}{
+		map[string]interface{}{"rating": 5, "text": "great"},
+	}))))
+	planner.Register("entity.photos", counted("entity.photos", soyusage.MemoryResolver(data.New([]interface{}{"a.jpg"}))))
+	planner.Register("site", counted("site", soyusage.MemoryResolver(data.New(map[string]interface{}{
+		"domain": "example.com",
+	}))))
+	planner.Register("other", counted("other", soyusage.MemoryResolver(data.String("unused"))))
+
+	must.BeEqual(t, []string{"entity", "entity.reviews", "site"}, planner.Plan(params))
+
+	got, err := planner.Load(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, data.New(map[string]interface{}{
+		"entity": map[string]interface{}{
+			"name": "Store",
+			"reviews": []interface{}{
+				map[string]interface{}{"rating": 5},
+			},
+		},
+		"site": map[string]interface{}{
+			"domain": "example.com",
+		},
+	}), data.Value(got))
+	must.BeEqual(t, map[string]int{"entity": 1, "entity.reviews": 1, "site": 1}, invoked)
+
+	planner.Register("site", soyusage.ResolverFunc(func(ctx context.Context, param *soyusage.Param) (data.Value, error) {
+		return nil, errors.New("unavailable")
+	}))
+	_, err = planner.Load(context.Background(), params)
+	must.BeEqual(t, `resolving "site": unavailable`, err.Error())
+}
+
+func TestPlannerUsedEntirely(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param entity
+	*/
+	{template .main}
+		{$entity|json}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	planner := soyusage.NewPlanner()
+	planner.Register("entity", soyusage.MemoryResolver(data.New(map[string]interface{}{
+		"name": "Store",
+	})))
+	planner.Register("entity.reviews", soyusage.MemoryResolver(data.New([]interface{}{
+		map[string]interface{}{"rating": 5, "text": "great"},
+	})))
+	planner.Register("entityType", soyusage.MemoryResolver(data.String("unused")))
+
+	must.BeEqual(t, []string{"entity", "entity.reviews"}, planner.Plan(params))
+
+	got, err := planner.Load(context.Background(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, data.New(map[string]interface{}{
+		"entity": map[string]interface{}{
+			"name": "Store",
+			"reviews": []interface{}{
+				map[string]interface{}{"rating": 5, "text": "great"},
+			},
+		},
+	}), data.Value(got))
+}
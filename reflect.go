@@ -0,0 +1,129 @@
+package soyusage
+
+import (
+	"reflect"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yext/soy/data"
+)
+
+var (
+	marshalerType = reflect.TypeOf((*data.Marshaler)(nil)).Elem()
+	valueType     = reflect.TypeOf((*data.Value)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// CheckAgainstType validates the usage of template params against the Go types
+// from which their values are built using data.New.
+// The bindings map the names of template params to their Go type. Params without
+// a binding are not checked.
+//
+// Struct fields are matched using the name conversion configured in data.DefaultStructOptions.
+// Values of interface types, and types implementing data.Marshaler, may contain any data
+// and are not checked.
+func CheckAgainstType(params Params, bindings map[string]reflect.Type) []Diagnostic {
+	var diagnostics []Diagnostic
+	for name, t := range bindings {
+		param, used := params[Name(name)]
+		if !used {
+			continue
+		}
+		diagnostics = append(diagnostics, checkType(Path{Name(name)}, param, t)...)
+	}
+	sortDiagnostics(diagnostics)
+	return diagnostics
+}
+
+func checkType(path Path, param *Param, t reflect.Type) []Diagnostic {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isDynamicType(t) {
+		return nil
+	}
+
+	var out []Diagnostic
+	if param.iterated && t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		out = append(out, newDiagnostic(path, param, "field is of type %v but is iterated as a list", t))
+	}
+	return append(out, checkTypeValue(path, param, t)...)
+}
+
+// checkTypeValue validates the fields accessed on a param, or the elements of a list param
+func checkTypeValue(path Path, param *Param, t reflect.Type) []Diagnostic {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if len(param.Children) == 0 || isDynamicType(t) {
+		return nil
+	}
+
+	var out []Diagnostic
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		// Fields of a list are the fields of its elements
+		return checkTypeValue(path, param, t.Elem())
+	case reflect.Map:
+		for name, child := range param.Children {
+			out = append(out, checkType(path.append(name), child, t.Elem())...)
+		}
+		return out
+	case reflect.Struct:
+		if t == timeType {
+			break
+		}
+		var fields = structFields(t)
+		for name, child := range param.Children {
+			if _, isMapIndex := name.(MapIndex); isMapIndex {
+				continue
+			}
+			childPath := path.append(name)
+			field, exists := fields[name.String()]
+			if !exists {
+				var candidates []string
+				for fieldName := range fields {
+					candidates = append(candidates, fieldName)
+				}
+				out = append(out, newDiagnostic(
+					childPath,
+					child,
+					"field %q does not exist in %v%s",
+					name,
+					t,
+					didYouMean(name.String(), candidates),
+				))
+				continue
+			}
+			out = append(out, checkType(childPath, child, field.Type)...)
+		}
+		return out
+	}
+	return append(out, newDiagnostic(path, param, "field is of type %v and has no fields", t))
+}
+
+// isDynamicType returns true if values of the type may contain any data
+func isDynamicType(t reflect.Type) bool {
+	return t.Kind() == reflect.Interface ||
+		t.Implements(marshalerType) ||
+		reflect.PtrTo(t).Implements(marshalerType)
+}
+
+// structFields lists the fields of a struct type by the name they will be given when
+// converted using data.New.
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	var out = make(map[string]reflect.StructField)
+	for name, index := range cachedStructFields(t) {
+		out[name] = t.Field(index)
+	}
+	return out
+}
+
+func structFieldKey(options data.StructOptions, name string) string {
+	if !options.LowerCamel {
+		return name
+	}
+	var firstRune, size = utf8.DecodeRuneInString(name)
+	return string(unicode.ToLower(firstRune)) + name[size:]
+}
@@ -0,0 +1,82 @@
+package soyusage_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+type testLocation struct {
+	Name       string
+	Address    *testAddress
+	Phones     []testPhone
+	Hours      map[string]testHours
+	Attributes map[string]interface{}
+	Updated    time.Time
+	internal   string
+}
+
+type testAddress struct {
+	City string
+}
+
+type testPhone struct {
+	Number string
+}
+
+type testHours struct {
+	Open string
+}
+
+func TestCheckAgainstType(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param loc
+	*/
+	{template .main}
+		{$loc.name}
+		{$loc.adress.city}
+		{$loc.address.city}
+		{$loc.address.city.first}
+		{foreach $phone in $loc.phones}
+			{$phone.number}
+			{$phone.extension}
+		{/foreach}
+		{foreach $hour in $loc.hours}
+			{$hour}
+		{/foreach}
+		{$loc.hours['mon'].open}
+		{$loc.attributes.anything.goes}
+		{$loc.updated.year}
+		{$loc.internal}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, diagnostic := range soyusage.CheckAgainstType(params, map[string]reflect.Type{
+		"loc": reflect.TypeOf(testLocation{}),
+	}) {
+		got = append(got, diagnostic.String())
+	}
+	must.BeEqual(t, []string{
+		`loc.address.city: field is of type string and has no fields`,
+		`loc.adress: field "adress" does not exist in soyusage_test.testLocation, did you mean "address"?`,
+		`loc.hours: field is of type map[string]soyusage_test.testHours but is iterated as a list`,
+		`loc.internal: field "internal" does not exist in soyusage_test.testLocation`,
+		`loc.phones.extension: field "extension" does not exist in soyusage_test.testPhone`,
+		`loc.updated: field is of type time.Time and has no fields`,
+	}, got)
+}
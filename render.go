@@ -0,0 +1,236 @@
+package soyusage
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yext/soy/ast"
+	"github.com/yext/soy/data"
+	"github.com/yext/soy/soyhtml"
+	"github.com/yext/soy/soymsg"
+	"github.com/yext/soy/template"
+)
+
+// RendererConfig defines configurable options for a Renderer
+type RendererConfig struct {
+	// Prune extracts the data used by a template before it is rendered
+	Prune bool
+	// Analysis holds the options used when analyzing each template
+	Analysis []Option
+}
+
+// RendererOption defines a function that modifies the configuration for a Renderer
+type RendererOption func(RendererConfig) RendererConfig
+
+// PruneBeforeRender extracts the data used by each template before it is rendered
+func PruneBeforeRender() RendererOption {
+	return func(c RendererConfig) RendererConfig {
+		c.Prune = true
+		return c
+	}
+}
+
+// AnalysisOptions sets the options used when analyzing each template
+func AnalysisOptions(options ...Option) RendererOption {
+	return func(c RendererConfig) RendererConfig {
+		c.Analysis = append(c.Analysis, options...)
+		return c
+	}
+}
+
+// Renderer renders the templates in a registry, analyzing each template the first
+// time its usage is needed and caching the result.
+//
+// The cached analysis is discarded when the SoyFiles of the registry change, such as
+// when it is updated in place by a Bundle watching its files. Invalidate may also
+// be called from a recompilation callback.
+type Renderer struct {
+	registry *template.Registry
+	tofu     *soyhtml.Tofu
+	config   RendererConfig
+
+	mu     sync.Mutex
+	files  []*ast.SoyFileNode
+	params map[string]Params
+
+	metrics RenderMetrics
+}
+
+// RenderMetrics counts the work done by a Renderer and the savings from pruning data.
+type RenderMetrics struct {
+	// Renders counts the templates rendered
+	Renders int64
+	// Analyses counts the templates analyzed
+	Analyses int64
+	// Extractions counts the data values pruned, whether before rendering or by Extract
+	Extractions int64
+	// ValuesIn counts the values in the data before pruning, including nested values
+	ValuesIn int64
+	// ValuesOut counts the values in the data after pruning, including nested values
+	ValuesOut int64
+}
+
+// NewRenderer creates a Renderer for the templates in the provided registry.
+func NewRenderer(registry *template.Registry, options ...RendererOption) *Renderer {
+	var config RendererConfig
+	for _, option := range options {
+		config = option(config)
+	}
+	return &Renderer{
+		registry: registry,
+		tofu:     soyhtml.NewTofu(registry),
+		config:   config,
+		params:   make(map[string]Params),
+	}
+}
+
+// Params returns the usage analysis for a template, analyzing it if needed.
+func (r *Renderer) Params(templateName string) (Params, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !sameFiles(r.files, r.registry.SoyFiles) {
+		r.files = r.registry.SoyFiles
+		r.params = make(map[string]Params)
+	}
+	if params, cached := r.params[templateName]; cached {
+		return params, nil
+	}
+	params, err := AnalyzeTemplate(templateName, r.registry, r.config.Analysis...)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt64(&r.metrics.Analyses, 1)
+	r.params[templateName] = params
+	return params, nil
+}
+
+// Invalidate discards all cached analysis, so templates will be analyzed again
+// when next rendered.
+func (r *Renderer) Invalidate() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.files = nil
+	r.params = make(map[string]Params)
+}
+
+// Extract returns a version of the input data containing only the values used by
+// the specified template. This allows data to be pruned before it is cached.
+//
+// Unlike the Extract function, values that are only checked for existence are
+// replaced with empty values of the same truthiness, so the template renders the
+// same output from the pruned data.
+func (r *Renderer) Extract(templateName string, in data.Map) (data.Map, error) {
+	params, err := r.Params(templateName)
+	if err != nil {
+		return nil, err
+	}
+	out, _ := extract(in, params, sameTruthiness).(data.Map)
+	atomic.AddInt64(&r.metrics.Extractions, 1)
+	atomic.AddInt64(&r.metrics.ValuesIn, int64(countValues(in)))
+	atomic.AddInt64(&r.metrics.ValuesOut, int64(countValues(out)))
+	return out, nil
+}
+
+// Metrics returns a snapshot of the metrics for this Renderer.
+func (r *Renderer) Metrics() RenderMetrics {
+	return RenderMetrics{
+		Renders:     atomic.LoadInt64(&r.metrics.Renders),
+		Analyses:    atomic.LoadInt64(&r.metrics.Analyses),
+		Extractions: atomic.LoadInt64(&r.metrics.Extractions),
+		ValuesIn:    atomic.LoadInt64(&r.metrics.ValuesIn),
+		ValuesOut:   atomic.LoadInt64(&r.metrics.ValuesOut),
+	}
+}
+
+// Render executes the named template using the given object, converted to a data.Map
+// in the same way as soyhtml.Tofu.Render.
+func (r *Renderer) Render(wr io.Writer, templateName string, obj interface{}) error {
+	var m data.Map
+	if obj != nil {
+		var ok bool
+		m, ok = data.New(obj).(data.Map)
+		if !ok {
+			return fmt.Errorf("invalid data type. expected map/struct, got %T", obj)
+		}
+	}
+	return r.NewRenderer(templateName).Execute(wr, m)
+}
+
+// NewRenderer returns a renderer for a single template, equivalent to
+// soyhtml.Tofu.NewRenderer.
+func (r *Renderer) NewRenderer(templateName string) *TemplateRenderer {
+	return &TemplateRenderer{
+		renderer: r,
+		soy:      r.tofu.NewRenderer(templateName),
+		name:     templateName,
+	}
+}
+
+// TemplateRenderer renders a single template, pruning its data if configured to do so.
+type TemplateRenderer struct {
+	renderer *Renderer
+	soy      *soyhtml.Renderer
+	name     string
+}
+
+// Inject sets the given data map as the $ij injected data.
+func (t *TemplateRenderer) Inject(ij data.Map) *TemplateRenderer {
+	t.soy.Inject(ij)
+	return t
+}
+
+// WithMessages provides a message bundle to use during execution.
+func (t *TemplateRenderer) WithMessages(bundle soymsg.Bundle) *TemplateRenderer {
+	t.soy.WithMessages(bundle)
+	return t
+}
+
+// Execute applies the template to the specified data object, and writes the output to wr.
+func (t *TemplateRenderer) Execute(wr io.Writer, obj data.Map) error {
+	// Missing templates are reported by the soy renderer
+	if _, found := t.renderer.registry.Template(t.name); found && t.renderer.config.Prune {
+		var err error
+		if obj, err = t.renderer.Extract(t.name, obj); err != nil {
+			return err
+		}
+	}
+	atomic.AddInt64(&t.renderer.metrics.Renders, 1)
+	return t.soy.Execute(wr, obj)
+}
+
+// sameFiles returns true if two lists contain the same soy files
+func sameFiles(a, b []*ast.SoyFileNode) bool {
+	if len(a) != len(b) || a == nil {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// countValues returns the number of values within a value, including itself
+func countValues(value data.Value) int {
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case data.Map:
+		var count = 1
+		for _, item := range v {
+			count += countValues(item)
+		}
+		return count
+	case data.List:
+		var count = 1
+		for _, item := range v {
+			count += countValues(item)
+		}
+		return count
+	}
+	return 1
+}
@@ -0,0 +1,86 @@
+package soyusage_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soy/soyhtml"
+	"github.com/yext/soyusage"
+)
+
+func TestRenderer(t *testing.T) {
+	const template = `
+	{namespace test}
+	/**
+	* @param loc
+	*/
+	{template .main}
+		{$loc.name}{if $loc.closed} (closed){/if}
+	{/template}
+	`
+	registry, err := soy.NewBundle().AddTemplateString("test.soy", template).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer := soyusage.NewRenderer(registry, soyusage.PruneBeforeRender())
+
+	in := map[string]interface{}{
+		"loc": map[string]interface{}{
+			"name":        "Store",
+			"closed":      true,
+			"description": "unused",
+		},
+		"other": "unused",
+	}
+	for i := 0; i < 2; i++ {
+		var out bytes.Buffer
+		if err := renderer.Render(&out, "test.main", in); err != nil {
+			t.Fatal(err)
+		}
+		must.BeEqual(t, "Store (closed)", strings.TrimSpace(out.String()))
+	}
+	must.BeEqual(t, soyusage.RenderMetrics{
+		Renders:     2,
+		Analyses:    1,
+		Extractions: 2,
+		ValuesIn:    12,
+		ValuesOut:   8,
+	}, renderer.Metrics())
+
+	pruned, err := renderer.Extract("test.main", data.New(in).(data.Map))
+	if err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, data.Map{
+		"loc": data.Map{
+			"name":   data.String("Store"),
+			"closed": data.Bool(true),
+		},
+	}, pruned)
+
+	// Replacing the registry in place, as a bundle watching files does, discards the analysis
+	recompiled, err := soy.NewBundle().AddTemplateString("test.soy", strings.Replace(template, "$loc.name", "$loc.description", 1)).Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	*registry = *recompiled
+	var out bytes.Buffer
+	if err := renderer.Render(&out, "test.main", in); err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, "unused (closed)", strings.TrimSpace(out.String()))
+	must.BeEqual(t, int64(2), renderer.Metrics().Analyses)
+
+	renderer.Invalidate()
+	if _, err := renderer.Params("test.main"); err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, int64(3), renderer.Metrics().Analyses)
+
+	err = renderer.Render(&out, "test.missing", in)
+	must.BeEqual(t, soyhtml.ErrTemplateNotFound, err)
+}
@@ -0,0 +1,145 @@
+package soyusage
+
+import "github.com/yext/soy/ast"
+
+// Required returns true if this param is accessed on every execution of the analyzed
+// templates, so a value must always be provided.
+//
+// Values are required if they are read, or their fields are accessed, outside of any if
+// statement, switch case, ternary branch or the second argument of ?:, and, or.
+// Fields of the elements of a list are required if they are accessed on every iteration
+// of a foreach loop over the list. Values that are only checked for existence, compared,
+// used as conditions or passed by reference do not need to be present, nor do values
+// following a null-safe access such as $a?.b.
+// Params declared as optional are never required, although their fields may be.
+func (p *Param) Required() bool {
+	return p.required && !p.optional
+}
+
+// conditionalScope creates a new scope "inside" the current scope, in which usages
+// may not occur on every execution, such as the second argument of ?:.
+func conditionalScope(s *scope) *scope {
+	cs := s.inner()
+	cs.conditional = true
+	return cs
+}
+
+// unconditional returns true if usages of the data ref with the provided key
+// occur on every execution of the templates.
+func (s *scope) unconditional(key Identifier) bool {
+	if s.conditional || len(s.guards) > 0 {
+		return false
+	}
+	return s.loopVariable == nil || s.loopVariable == key
+}
+
+// analyzeNullable analyzes a node whose value may be null without causing an error,
+// such as a condition.
+func analyzeNullable(s *scope, usageType UsageType, node ast.Node) error {
+	if dataRef, isDataRef := node.(*ast.DataRefNode); isDataRef {
+		_, err := recordDataRefUsage(s, Usage{Type: usageType, nullable: true}, dataRef)
+		return err
+	}
+	return analyzeNode(s, usageType, node)
+}
+
+// analyzeCondition analyzes a node whose value is used as a condition.
+func analyzeCondition(s *scope, node ast.Node) error {
+	if err := analyzeNullable(s, UsageFull, node); err != nil {
+		return err
+	}
+	recordType(s, TypeBool, node)
+	recordNullChecked(s, node)
+	return nil
+}
+
+// recordRequired marks the params that must have a value whenever a data ref is evaluated.
+// Each value that is accessed is required until a null-safe access is reached. The value
+// of the data ref itself is only required if it is read.
+func recordRequired(s *scope, usage Usage, node *ast.DataRefNode) {
+	if !s.unconditional(Name(node.Key)) {
+		return
+	}
+	for i, access := range node.Access {
+		if isNullSafe(access) {
+			return
+		}
+		for _, param := range usage.chain[i] {
+			param.required = true
+		}
+	}
+	if usage.nullable {
+		return
+	}
+	switch usage.Type {
+	case UsageFull, UsageUnknown, UsageMeta:
+		for _, param := range usage.chain[len(node.Access)] {
+			param.required = true
+		}
+	}
+}
+
+// accessChain returns the params referenced by each prefix of a data ref, starting
+// with the params for its key and ending with the params for the whole data ref.
+func accessChain(s *scope, node *ast.DataRefNode, params []*Param) [][]*Param {
+	var current []*Param
+	for _, param := range params {
+		if !param.isConstant() {
+			current = append(current, param)
+		}
+	}
+	var chain = [][]*Param{current}
+	for _, access := range node.Access {
+		current = accessedChildren(s, current, access)
+		chain = append(chain, current)
+	}
+	return chain
+}
+
+func isNullSafe(access ast.Node) bool {
+	switch v := access.(type) {
+	case *ast.DataRefKeyNode:
+		return v.NullSafe
+	case *ast.DataRefIndexNode:
+		return v.NullSafe
+	case *ast.DataRefExprNode:
+		return v.NullSafe
+	}
+	return false
+}
+
+// accessedChildren returns the params reached by an access from each of the provided params.
+// Only params that have already been recorded are returned.
+func accessedChildren(s *scope, params []*Param, access ast.Node) []*Param {
+	var names []interface{}
+	switch v := access.(type) {
+	case *ast.DataRefKeyNode:
+		names = []interface{}{v.Key}
+	case *ast.DataRefIndexNode:
+		names = []interface{}{v.Index}
+	case *ast.DataRefExprNode:
+		values, err := constantValues(s, v.Arg)
+		if err != nil {
+			return nil
+		}
+		names = orNonConstant(values)
+	}
+	var out []*Param
+	for _, param := range params {
+		for _, name := range names {
+			switch name := name.(type) {
+			case int:
+				out = append(out, param)
+			case string:
+				if child, exists := param.Children[Name(name)]; exists {
+					out = append(out, child)
+				}
+			case nonConstant:
+				if child, exists := param.Children[MapIndex{}]; exists {
+					out = append(out, child)
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package soyusage_test
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestRequired(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		expected     []string
+	}{
+		{
+			name: "unconditional access is required",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				* @param? site
+				*/
+				{template .main}
+					{$loc.name}
+					{$loc.address.city}
+					{$site.domain}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"loc",
+				"loc.address",
+				"loc.address.city",
+				"loc.name",
+				"site.domain",
+			},
+		},
+		{
+			name: "guarded access is optional",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{if $loc.description}
+						{$loc.description}
+					{/if}
+					{if $loc.closed}
+						Closed
+					{elseif $loc.hours}
+						{$loc.hours.today}
+					{/if}
+					{switch $loc.type}
+						{case 'store'}{$loc.storeId}
+					{/switch}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"loc",
+			},
+		},
+		{
+			name: "expressions with conditional evaluation",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{$loc.name ?: $loc.fallbackName}
+					{$loc.featured ? $loc.badge : ''}
+					{if $loc.open and $loc.hours.today}Open{/if}
+					{$loc.address?.city}
+					{$loc.rating.value}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"loc",
+				"loc.rating",
+				"loc.rating.value",
+			},
+		},
+		{
+			name: "fields of list elements are required on every iteration",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{foreach $phone in $loc.phones}
+						{$phone.number}
+						{$loc.name}
+						{if $phone.ext}{$phone.ext}{/if}
+					{/foreach}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"loc",
+				"loc.phones",
+				"loc.phones.number",
+			},
+		},
+		{
+			name: "params passed to calls",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{call .address}
+						{param address: $loc.address /}
+					{/call}
+					{if $loc.showHours}
+						{call .hours}
+							{param hours: $loc.hours /}
+						{/call}
+					{/if}
+				{/template}
+
+				/**
+				* @param address
+				*/
+				{template .address}
+					{$address.city}
+				{/template}
+
+				/**
+				* @param hours
+				*/
+				{template .hours}
+					{$hours.today}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: []string{
+				"loc",
+				"loc.address",
+				"loc.address.city",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			requiredPaths(nil, params, &got)
+			sort.Strings(got)
+			must.BeEqual(t, test.expected, got)
+		})
+	}
+}
+
+func requiredPaths(path soyusage.Path, params soyusage.Params, out *[]string) {
+	for name, param := range params {
+		childPath := append(append(soyusage.Path(nil), path...), name)
+		if param.Required() {
+			*out = append(*out, childPath.String())
+		}
+		requiredPaths(childPath, param.Children, out)
+	}
+}
@@ -9,6 +9,20 @@ type scope struct {
 	callStack    []*scope
 	parameters   Params
 	variables    map[Identifier][]*Param
+	// constants holds the known values of variables that are constant within
+	// this scope, such as the value of a switch within one of its cases
+	constants map[Identifier][]interface{}
+	// guards lists the conditions that must hold for usages in this scope
+	guards []Guard
+	// conditional is set if usages in this scope may not occur on every execution
+	conditional bool
+	// loopVariable names the variable of the innermost foreach loop containing this
+	// scope, whose usages occur on every iteration
+	loopVariable Identifier
+	// loops lists the params iterated by the foreach loops containing this scope
+	loops []*Param
+	// headerParams caches the header params of templates, shared by all scopes
+	headerParams headerParams
 	config       Config
 }
 
@@ -39,6 +53,12 @@ func (s *scope) inner() *scope {
 		callStack:    nil,
 		parameters:   s.parameters,
 		variables:    make(map[Identifier][]*Param),
+		constants:    make(map[Identifier][]interface{}),
+		guards:       s.guards,
+		conditional:  s.conditional,
+		loopVariable: s.loopVariable,
+		loops:        s.loops,
+		headerParams: s.headerParams,
 		config:       s.config,
 	}
 
@@ -49,9 +69,19 @@ func (s *scope) inner() *scope {
 	for name, params := range s.variables {
 		out.variables[name] = params
 	}
+	for name, constants := range s.constants {
+		out.constants[name] = constants
+	}
 	return out
 }
 
+// bind assigns the params for a variable. Any constant values known for a
+// previous variable with the same name no longer apply.
+func (s *scope) bind(name Identifier, params []*Param) {
+	s.variables[name] = params
+	delete(s.constants, name)
+}
+
 // call creates a child scope as a result of a call
 // parameters and variables are reset
 func (s *scope) call(templateName string) *scope {
@@ -60,6 +90,11 @@ func (s *scope) call(templateName string) *scope {
 		templateName: templateName,
 		parameters:   make(Params),
 		variables:    make(map[Identifier][]*Param),
+		constants:    make(map[Identifier][]interface{}),
+		guards:       s.guards,
+		conditional:  s.conditional || s.loopVariable != nil,
+		loops:        s.loops,
+		headerParams: s.headerParams,
 		config:       s.config,
 	}
 
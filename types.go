@@ -0,0 +1,186 @@
+package soyusage
+
+import (
+	"strings"
+
+	"github.com/yext/soy/ast"
+)
+
+// ValueType is a set of the kinds of value a param may hold, inferred from the
+// ways in which it was used. The zero value indicates that nothing is known.
+type ValueType uint8
+
+const (
+	// TypeString is inferred for params compared with strings, or passed to
+	// functions requiring a string.
+	TypeString ValueType = 1 << iota
+	// TypeNumber is inferred for params used in arithmetic or numeric comparisons,
+	// compared with numbers, or passed to functions requiring a number.
+	TypeNumber
+	// TypeBool is inferred for params used as conditions. Any value may be used as a
+	// condition, so this is only reported if no other type was inferred.
+	TypeBool
+	// TypeList is inferred for params iterated by a foreach loop, accessed by index,
+	// or passed to length().
+	TypeList
+	// TypeMap is inferred for params whose fields are accessed, or that are passed
+	// to keys() or augmentMap().
+	TypeMap
+)
+
+// valueTypeNames lists the names of each type, in the order they are displayed
+var valueTypeNames = []struct {
+	valueType ValueType
+	name      string
+}{
+	{TypeString, "string"},
+	{TypeNumber, "number"},
+	{TypeBool, "bool"},
+	{TypeList, "list"},
+	{TypeMap, "map"},
+}
+
+// scalarTypes are the types that cannot be held by a single value at the same time.
+// Lists are excluded, since usage of a list param may describe its elements.
+const scalarTypes = TypeString | TypeNumber | TypeMap
+
+// Has returns true if this set includes all of the types in other
+func (t ValueType) Has(other ValueType) bool {
+	return t&other == other
+}
+
+// Conflict returns true if the set includes types that cannot be held by the same
+// value, such as a string and a number. A list may be combined with another type,
+// which describes the elements of the list.
+func (t ValueType) Conflict() bool {
+	scalar := t & scalarTypes
+	return scalar&(scalar-1) != 0
+}
+
+func (t ValueType) String() string {
+	if t == 0 {
+		return "unknown"
+	}
+	var names []string
+	for _, typeName := range valueTypeNames {
+		if t.Has(typeName.valueType) {
+			names = append(names, typeName.name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Type returns the types inferred for the value of this param from its usage.
+// As values within lists share the param of the list, a list type may be combined
+// with the type of its elements.
+func (p *Param) Type() ValueType {
+	var t = p.types
+	if p.iterated {
+		t |= TypeList
+	}
+	for name := range p.Children {
+		if _, isName := name.(Name); isName {
+			t |= TypeMap
+			break
+		}
+	}
+	for _, value := range p.compared {
+		switch value.(type) {
+		case string:
+			t |= TypeString
+		case int:
+			t |= TypeNumber
+		}
+	}
+	if t != TypeBool {
+		t &^= TypeBool
+	}
+	return t
+}
+
+// TypeConflicts lists the params for which conflicting types were inferred, such as a
+// field used both in arithmetic and compared with a string.
+// The results are sorted by path.
+func TypeConflicts(params Params) []Diagnostic {
+	var out []Diagnostic
+	for name, param := range params {
+		out = append(out, typeConflicts(Path{name}, param)...)
+	}
+	sortDiagnostics(out)
+	return out
+}
+
+func typeConflicts(path Path, param *Param) []Diagnostic {
+	var out []Diagnostic
+	if t := param.Type(); t.Conflict() {
+		out = append(out, newDiagnostic(path, param, "field is used as incompatible types: %v", t))
+	}
+	for name, child := range param.Children {
+		out = append(out, typeConflicts(path.append(name), child)...)
+	}
+	return out
+}
+
+// functionTypes lists the type required for the arguments of functions
+var functionTypes = map[string]ValueType{
+	"length":        TypeList,
+	"keys":          TypeMap,
+	"augmentMap":    TypeMap,
+	"quoteKeysIfJs": TypeMap,
+	"round":         TypeNumber,
+	"floor":         TypeNumber,
+	"ceiling":       TypeNumber,
+	"min":           TypeNumber,
+	"max":           TypeNumber,
+	"randomInt":     TypeNumber,
+	"strContains":   TypeString,
+}
+
+// analyzeTyped analyzes nodes in the same way as analyzeNode, and records that
+// any params referenced directly by the nodes are of the provided type.
+func analyzeTyped(s *scope, valueType ValueType, usageType UsageType, node ...ast.Node) error {
+	if err := analyzeNode(s, usageType, node...); err != nil {
+		return err
+	}
+	for _, node := range node {
+		recordType(s, valueType, node)
+	}
+	return nil
+}
+
+// analyzeAdd analyzes the arguments of an addition. Soy concatenates strings, so
+// the arguments are only known to be numbers when one of them is a number literal.
+func analyzeAdd(s *scope, node *ast.AddNode) error {
+	if err := analyzeNode(s, UsageFull, node.Arg1, node.Arg2); err != nil {
+		return err
+	}
+	if isNumberLiteral(node.Arg1) {
+		recordType(s, TypeNumber, node.Arg2)
+	}
+	if isNumberLiteral(node.Arg2) {
+		recordType(s, TypeNumber, node.Arg1)
+	}
+	return nil
+}
+
+func isNumberLiteral(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.IntNode, *ast.FloatNode:
+		return true
+	}
+	return false
+}
+
+// recordType records the type of the params referenced by a data ref.
+// Nodes other than data refs are ignored.
+func recordType(s *scope, valueType ValueType, node ast.Node) {
+	dataRef, isDataRef := node.(*ast.DataRefNode)
+	if !isDataRef {
+		return
+	}
+	for _, param := range resolveDataRef(s, dataRef) {
+		if !param.isConstant() {
+			param.types |= valueType
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestType(t *testing.T) {
+	var tests = []struct {
+		name         string
+		templates    map[string]string
+		templateName string
+		expected     map[string]string
+	}{
+		{
+			name: "infers types from operators and functions",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param a
+				* @param b
+				* @param c
+				* @param d
+				* @param e
+				* @param f
+				* @param g
+				*/
+				{template .main}
+					{$a + 1}
+					{$b > 2 ? 'big' : 'small'}
+					{round($c)}
+					{if $d}{$d}{/if}
+					{if strContains($e, 'x')}Yes{/if}
+					{$f + 'suffix'}
+					{$g}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]string{
+				"a": "number",
+				"b": "number",
+				"c": "number",
+				"d": "bool",
+				"e": "string",
+				"f": "unknown",
+				"g": "unknown",
+			},
+		},
+		{
+			name: "infers lists and maps from structure",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				* @param tags
+				* @param hours
+				*/
+				{template .main}
+					{foreach $phone in $loc.phones}
+						{$phone.number}
+					{/foreach}
+					{$tags[0]}
+					{length(keys($hours))}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]string{
+				"loc":               "map",
+				"loc.phones":        "list|map",
+				"loc.phones.number": "unknown",
+				"tags":              "list",
+				"hours":             "map",
+			},
+		},
+		{
+			name: "infers types from comparisons",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param status
+				* @param count
+				*/
+				{template .main}
+					{if $status == 'OPEN'}Open{/if}
+					{switch $count}
+						{case 1}One
+					{/switch}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]string{
+				"status": "string",
+				"count":  "number",
+			},
+		},
+		{
+			name: "propagates types through calls",
+			templates: map[string]string{
+				"test.soy": `
+				{namespace test}
+				/**
+				* @param loc
+				*/
+				{template .main}
+					{call .count}
+						{param value: $loc.reviewCount /}
+					{/call}
+				{/template}
+
+				/**
+				* @param value
+				*/
+				{template .count}
+					{$value * 2}
+				{/template}
+			`,
+			},
+			templateName: "test.main",
+			expected: map[string]string{
+				"loc":             "map",
+				"loc.reviewCount": "number",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bundle := soy.NewBundle()
+			for name, content := range test.templates {
+				bundle = bundle.AddTemplateString(name, content)
+			}
+			registry, err := bundle.Compile()
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err := soyusage.AnalyzeTemplate(test.templateName, registry)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got = make(map[string]string)
+			paramTypes(nil, params, got)
+			must.BeEqual(t, test.expected, got)
+		})
+	}
+}
+
+func paramTypes(path soyusage.Path, params soyusage.Params, out map[string]string) {
+	for name, param := range params {
+		childPath := append(append(soyusage.Path(nil), path...), name)
+		out[childPath.String()] = param.Type().String()
+		paramTypes(childPath, param.Children, out)
+	}
+}
+
+func TestTypeConflicts(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param loc
+	*/
+	{template .main}
+		{if $loc.rating > 3}Good{/if}
+		{if $loc.rating == 'none'}No rating{/if}
+		{$loc.name.first}
+		{if $loc.name == 'Unknown'}Unnamed{/if}
+		{foreach $tag in $loc.tags}
+			{if $tag == 'new'}New{/if}
+		{/foreach}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, diagnostic := range soyusage.TypeConflicts(params) {
+		got = append(got, diagnostic.String())
+	}
+	must.BeEqual(t, []string{
+		"loc.name: field is used as incompatible types: string|map",
+		"loc.rating: field is used as incompatible types: string|number",
+	}, got)
+}
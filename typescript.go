@@ -0,0 +1,178 @@
+package soyusage
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// GenerateTypeScript generates TypeScript declarations describing the data used by
+// a template, as described by its parameter tree.
+//
+// The root interface is named interfaceName and has a property for each param.
+// Properties with children become nested interfaces, properties accessed via a map
+// index have an index signature and properties iterated in a foreach loop become arrays.
+// Properties are optional unless they are required by the template, so they are optional
+// if the param was declared as optional, or is only accessed after checking that it exists.
+// The types of properties without children are those inferred from their usage.
+func GenerateTypeScript(interfaceName string, params Params) ([]byte, error) {
+	g := &tsGenerator{
+		names: map[string]struct{}{interfaceName: {}},
+	}
+	g.queue = append(g.queue, namedParams{name: interfaceName, params: params})
+	for len(g.queue) > 0 {
+		next := g.queue[0]
+		g.queue = g.queue[1:]
+		g.writeInterface(next)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by soyusage. DO NOT EDIT.\n")
+	out.Write(g.body.Bytes())
+	return out.Bytes(), nil
+}
+
+type tsGenerator struct {
+	body  bytes.Buffer
+	queue []namedParams
+	names map[string]struct{}
+}
+
+func (g *tsGenerator) writeInterface(s namedParams) {
+	fmt.Fprintf(&g.body, "\nexport interface %s {\n", s.name)
+	for _, name := range sortedNames(s.params) {
+		if _, isMapIndex := name.(MapIndex); isMapIndex {
+			continue
+		}
+		param := s.params[name]
+		if templates := usageTemplates(param); len(templates) > 0 {
+			fmt.Fprintf(&g.body, "  /** Used by %s. */\n", strings.Join(templates, ", "))
+		}
+		var optional string
+		if !param.Required() {
+			optional = "?"
+		}
+		fmt.Fprintf(&g.body, "  %s%s: %s;\n", tsPropertyName(name.String()), optional, g.tsType(s.name+tsTypeName(name.String()), param))
+	}
+	fmt.Fprintf(&g.body, "}\n")
+}
+
+// tsType returns the type to be used for a param, queueing any nested interfaces
+// that need to be generated.
+func (g *tsGenerator) tsType(name string, param *Param) string {
+	var valueType string
+	switch {
+	case hasMapIndex(param.Children):
+		valueType = "{ [key: string]: " + g.tsType(name+"Value", mergeChildren(param)) + " }"
+	case len(param.Children) > 0:
+		interfaceName := g.uniqueName(name)
+		g.queue = append(g.queue, namedParams{name: interfaceName, params: param.Children})
+		valueType = interfaceName
+	default:
+		valueType = tsLeafType(param)
+	}
+	if param.iterated {
+		return tsArray(valueType)
+	}
+	return valueType
+}
+
+func tsArray(elementType string) string {
+	if strings.Contains(elementType, " ") {
+		return "Array<" + elementType + ">"
+	}
+	return elementType + "[]"
+}
+
+func (g *tsGenerator) uniqueName(name string) string {
+	var out = name
+	for i := 2; ; i++ {
+		if _, exists := g.names[out]; !exists {
+			break
+		}
+		out = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[out] = struct{}{}
+	return out
+}
+
+// tsValueTypes lists the TypeScript type for each inferred type other than lists.
+// Any value may be used as a condition, so TypeBool does not restrict the type.
+var tsValueTypes = []struct {
+	valueType ValueType
+	name      string
+}{
+	{TypeString, "string"},
+	{TypeNumber, "number"},
+	{TypeMap, "{ [key: string]: unknown }"},
+}
+
+// tsLeafType selects a type for a param without children based on the types inferred
+// from its usage, or how it was used if no types were inferred.
+// Values within lists share the param of the list, so the inferred types other than
+// list describe the elements of iterated params.
+func tsLeafType(param *Param) string {
+	var (
+		inferred = param.Type()
+		names    []string
+	)
+	for _, tsType := range tsValueTypes {
+		if inferred.Has(tsType.valueType) {
+			names = append(names, tsType.name)
+		}
+	}
+	valueType := strings.Join(names, " | ")
+	if len(names) == 0 {
+		valueType = tsUsageType(param)
+	}
+	if inferred.Has(TypeList) && !param.iterated {
+		return tsArray(valueType)
+	}
+	return valueType
+}
+
+// tsUsageType selects a type for a param based on how it was used
+func tsUsageType(param *Param) string {
+	var usageTypes = make(map[UsageType]bool)
+	for _, usage := range param.Usage {
+		usageTypes[usage.Type] = true
+	}
+	switch {
+	case usageTypes[UsageUnknown]:
+		return "unknown"
+	case usageTypes[UsageFull]:
+		return "string | number | boolean"
+	}
+	return "unknown"
+}
+
+func tsPropertyName(name string) string {
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return strconv.Quote(name)
+}
+
+// tsTypeName converts a property name into a form suitable for use
+// as part of an interface name.
+func tsTypeName(name string) string {
+	var out strings.Builder
+	upper := true
+	for _, r := range name {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			upper = true
+			continue
+		}
+		if upper {
+			out.WriteString(strings.ToUpper(string(r)))
+			upper = false
+			continue
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
@@ -0,0 +1,102 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soyusage"
+)
+
+func TestGenerateTypeScript(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param entity
+	* @param? banner
+	* @param key
+	*/
+	{template .main}
+		{$entity.name}
+		{if $entity.website}
+			{$entity.website}
+		{/if}
+		{foreach $phone in $entity.phones}
+			{$phone.number}
+		{/foreach}
+		{foreach $tag in $entity.tags}
+			{$tag}
+		{/foreach}
+		{$entity.hours[$key].open}
+		{$entity['c_custom-field']}
+		{$banner}
+		{if $entity.address}
+			{$entity.address.city}
+		{/if}
+		{if $entity.rating > 3}Good{/if}
+		{if $entity.status == 'OPEN'}Open{/if}
+		{length($entity.photos)}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := soyusage.GenerateTypeScript("MainParams", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	must.BeEqual(t, `// Code generated by soyusage. DO NOT EDIT.
+
+export interface MainParams {
+  /** Used by test.main. */
+  banner?: string | number | boolean;
+  /** Used by test.main. */
+  entity: MainParamsEntity;
+  /** Used by test.main. */
+  key: string | number | boolean;
+}
+
+export interface MainParamsEntity {
+  /** Used by test.main. */
+  address?: MainParamsEntityAddress;
+  /** Used by test.main. */
+  "c_custom-field": string | number | boolean;
+  /** Used by test.main. */
+  hours: { [key: string]: MainParamsEntityHoursValue };
+  /** Used by test.main. */
+  name: string | number | boolean;
+  /** Used by test.main. */
+  phones: MainParamsEntityPhones[];
+  /** Used by test.main. */
+  photos: unknown[];
+  /** Used by test.main. */
+  rating: number;
+  /** Used by test.main. */
+  status?: string;
+  /** Used by test.main. */
+  tags: Array<string | number | boolean>;
+  /** Used by test.main. */
+  website?: string | number | boolean;
+}
+
+export interface MainParamsEntityAddress {
+  /** Used by test.main. */
+  city?: string | number | boolean;
+}
+
+export interface MainParamsEntityHoursValue {
+  /** Used by test.main. */
+  open: string | number | boolean;
+}
+
+export interface MainParamsEntityPhones {
+  /** Used by test.main. */
+  number: string | number | boolean;
+}
+`, string(got))
+}
@@ -1,6 +1,8 @@
 package soyusage
 
 import (
+	"sort"
+
 	"github.com/yext/soy/ast"
 )
 
@@ -38,6 +40,25 @@ type (
 
 		// A constant value for this param
 		constant interface{}
+		// iterated is set if this param was used as the list in a foreach loop
+		iterated bool
+		// optional is set for template params declared as optional
+		optional bool
+		// compared lists the constant values this param was compared against
+		compared []interface{}
+		// types holds the types inferred from usage other than iteration, field access
+		// and comparison, which are recorded separately
+		types ValueType
+		// declarations lists the types declared for this param by header params
+		declarations []Declaration
+		// required is set if this param is accessed on every execution
+		required bool
+		// nullChecked is set if the templates check whether this param is null
+		nullChecked bool
+		// nestedIteration is set if this param was iterated by a foreach loop within
+		// another loop over the same param, so its loop variables may refer to
+		// different elements
+		nestedIteration bool
 	}
 
 	// Identifier names a parameter
@@ -48,6 +69,9 @@ type (
 	Name     string
 	MapIndex struct{}
 
+	// Path identifies a parameter by the sequence of names from a root parameter.
+	Path []Identifier
+
 	// UsageType specifies the manner in which a parameter was used.
 	UsageType int
 
@@ -57,10 +81,34 @@ type (
 		// Template provides the name of the template containing the usage.
 		Template string
 
-		node ast.Node
+		node       ast.Node
+		directives []*ast.PrintDirectiveNode
+		guards     []Guard
+		// nullable is set if the value may be null without causing an error,
+		// such as when used as a condition
+		nullable bool
+		// chain lists the params referenced by each prefix of the data ref,
+		// from its key to the whole data ref
+		chain [][]*Param
 	}
 )
 
+func (u UsageType) String() string {
+	switch u {
+	case UsageFull:
+		return "full"
+	case UsageUnknown:
+		return "unknown"
+	case UsageMeta:
+		return "meta"
+	case UsageExists:
+		return "exists"
+	case UsageReference:
+		return "reference"
+	}
+	return "undefined"
+}
+
 func (n Name) String() string {
 	return string(n)
 }
@@ -69,12 +117,31 @@ func (MapIndex) String() string {
 	return "[?]"
 }
 
+func (p Path) String() string {
+	var out string
+	for i, name := range p {
+		if _, isMapIndex := name.(MapIndex); isMapIndex || i == 0 {
+			out += name.String()
+			continue
+		}
+		out += "." + name.String()
+	}
+	return out
+}
+
+func (p Path) append(name Identifier) Path {
+	out := make(Path, len(p), len(p)+1)
+	copy(out, p)
+	return append(out, name)
+}
+
 func (p *Param) addUsageToLeaves(usage Usage) {
 	if len(p.Children) == 0 {
 		for _, otherUsage := range p.Usage {
 			if otherUsage.Template == usage.Template &&
 				otherUsage.Type == usage.Type &&
-				otherUsage.node.Position() == usage.node.Position() {
+				otherUsage.node.Position() == usage.node.Position() &&
+				sameGuards(otherUsage.guards, usage.guards) {
 				return
 			}
 		}
@@ -105,10 +172,144 @@ func (u Usage) Node() ast.Node {
 	return u.node
 }
 
+// leafUsage returns all usages recorded for this param and its descendants.
+func (p *Param) leafUsage() []Usage {
+	var out = append([]Usage(nil), p.Usage...)
+	for _, child := range p.Children {
+		out = append(out, child.leafUsage()...)
+	}
+	return out
+}
+
+// usedEntirely returns true if this param has a usage that requires the entire value
+func (p *Param) usedEntirely() bool {
+	return len(p.entireUsage()) > 0
+}
+
+// entireUsage returns the usages that require the entire value of this param.
+// Usages of a param that already has children are recorded on its leaves, so the
+// usages of descendants that refer to this param are included.
+func (p *Param) entireUsage() []Usage {
+	var out []Usage
+	for _, usage := range p.Usage {
+		if usage.Type == UsageFull || usage.Type == UsageUnknown {
+			out = append(out, usage)
+		}
+	}
+	for _, child := range p.Children {
+		for _, usage := range child.leafUsage() {
+			if (usage.Type == UsageFull || usage.Type == UsageUnknown) && usage.reads(p) {
+				out = append(out, usage)
+			}
+		}
+	}
+	return out
+}
+
+// reads returns true if the data ref of a usage refers to a param, rather than one of
+// its descendants.
+func (u Usage) reads(param *Param) bool {
+	if len(u.chain) == 0 {
+		return false
+	}
+	for _, p := range u.chain[len(u.chain)-1] {
+		if p == param {
+			return true
+		}
+	}
+	return false
+}
+
+// hasUsage returns true if this param has a usage of the specified type
+func (p *Param) hasUsage(usageType UsageType) bool {
+	for _, usage := range p.Usage {
+		if usage.Type == usageType {
+			return true
+		}
+	}
+	return false
+}
+
+// isRead returns true if the value of this param, or one of its descendants,
+// is used for anything other than an existence check.
+func (p *Param) isRead() bool {
+	for _, usage := range p.Usage {
+		if usage.Type != UsageExists {
+			return true
+		}
+	}
+	for _, child := range p.Children {
+		if child.isRead() {
+			return true
+		}
+	}
+	return false
+}
+
+// Directives lists the print directives applied when the param was printed.
+// This will be empty for usages other than printing a param directly.
+func (u Usage) Directives() []*ast.PrintDirectiveNode {
+	return u.directives
+}
+
 func (p *Param) isConstant() bool {
 	return p.constant != nil
 }
 
+// ComparedValues lists the constant values this param was compared against, using
+// == or != or as the value of a switch statement. This may be used to identify
+// values that are expected to be one of a fixed set, such as an enum.
+// String values are listed first, followed by numbers, each in sorted order.
+func (p *Param) ComparedValues() []interface{} {
+	var out = append([]interface{}(nil), p.compared...)
+	sort.Slice(out, func(i, j int) bool {
+		iString, iIsString := out[i].(string)
+		jString, jIsString := out[j].(string)
+		if iIsString != jIsString {
+			return iIsString
+		}
+		if iIsString {
+			return iString < jString
+		}
+		iInt, _ := out[i].(int)
+		jInt, _ := out[j].(int)
+		return iInt < jInt
+	})
+	return out
+}
+
+func (p *Param) addCompared(values ...interface{}) {
+	for _, value := range values {
+		var exists bool
+		for _, existing := range p.compared {
+			if existing == value {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			p.compared = append(p.compared, value)
+		}
+	}
+}
+
+// merge adds the usage and children of another param to this param
+func (p *Param) merge(other *Param) {
+	p.Usage = append(p.Usage, other.Usage...)
+	p.iterated = p.iterated || other.iterated
+	p.types |= other.types
+	p.required = p.required || other.required
+	p.nullChecked = p.nullChecked || other.nullChecked
+	p.nestedIteration = p.nestedIteration || other.nestedIteration
+	for _, declaration := range other.declarations {
+		p.declare(declaration)
+	}
+	p.addCompared(other.compared...)
+	for name, child := range other.Children {
+		p.getChildOrNew(name).merge(child)
+	}
+}
+
 func newParam() *Param {
 	return &Param{
 		Children: make(Params),
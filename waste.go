@@ -0,0 +1,204 @@
+package soyusage
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/yext/soy/data"
+)
+
+// WasteReport attributes the size of sample input data to the paths within it,
+// distinguishing the bytes used by templates from those discarded by Extract.
+// Sizes are measured as compact JSON and aggregated across all samples added.
+//
+// Paths are formed by joining map keys with ".". The elements of a list are
+// combined under the path of the list, suffixed with "[]".
+type WasteReport struct {
+	// Samples counts the number of input values added to the report
+	Samples int
+
+	paths   map[string]*PathWaste
+	removed map[string]struct{}
+}
+
+// PathWaste describes the size of the values at a single path.
+type PathWaste struct {
+	// Path identifies the values, the root value has an empty path
+	Path string
+	// Used counts the bytes retained by Extract
+	Used int
+	// Discarded counts the bytes removed by Extract
+	Discarded int
+}
+
+// NewWasteReport creates an empty report.
+func NewWasteReport() *WasteReport {
+	return &WasteReport{
+		paths:   make(map[string]*PathWaste),
+		removed: make(map[string]struct{}),
+	}
+}
+
+// Add records the sizes of the values within in, as extracted according to the provided
+// usage analysis. The extracted data is returned.
+func (r *WasteReport) Add(in data.Value, params Params) data.Value {
+	out := Extract(in, params)
+	r.Samples++
+	r.add("", in, out)
+	return out
+}
+
+// Total returns the size of all the samples.
+func (r *WasteReport) Total() PathWaste {
+	if total, exists := r.paths[""]; exists {
+		return *total
+	}
+	return PathWaste{}
+}
+
+// Paths returns the sizes for every path in the samples, sorted by path.
+// Values within subtrees that were removed entirely are not included.
+func (r *WasteReport) Paths() []PathWaste {
+	var out []PathWaste
+	for _, path := range r.paths {
+		out = append(out, *path)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Path < out[j].Path
+	})
+	return out
+}
+
+// Unused returns up to n of the largest subtrees that were not used by the templates,
+// ordered by the number of bytes discarded.
+// This includes subtrees that were only checked for existence, and so replaced with
+// a smaller value.
+func (r *WasteReport) Unused(n int) []PathWaste {
+	if n <= 0 {
+		return nil
+	}
+	var out []PathWaste
+	for path := range r.removed {
+		if waste := r.paths[path]; waste.Discarded > 0 {
+			out = append(out, *waste)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Discarded != out[j].Discarded {
+			return out[i].Discarded > out[j].Discarded
+		}
+		return out[i].Path < out[j].Path
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// add records the sizes of the values within in and returns the size of in and out.
+// Sizes are computed from the sizes of child values, so each value is only visited once.
+func (r *WasteReport) add(path string, in, out data.Value) (inSize, outSize int) {
+	if in == nil {
+		return jsonSize(in), jsonSize(out)
+	}
+	waste, exists := r.paths[path]
+	if !exists {
+		waste = &PathWaste{Path: path}
+		r.paths[path] = waste
+	}
+	if out == nil {
+		inSize = jsonSize(in)
+		waste.Discarded += inSize
+		r.removed[path] = struct{}{}
+		return inSize, 0
+	}
+
+	switch inValue := in.(type) {
+	case data.Map:
+		outMap, isMap := out.(data.Map)
+		if !isMap {
+			r.removed[path] = struct{}{}
+			inSize, outSize = jsonSize(in), jsonSize(out)
+			break
+		}
+		var kept int
+		for key, value := range inValue {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			outValue, isKept := outMap[key]
+			childIn, childOut := r.add(childPath, value, outValue)
+			inSize += keySize(key) + childIn
+			if isKept {
+				outSize += keySize(key) + childOut
+				kept++
+			}
+		}
+		inSize += delimiterSize(len(inValue))
+		outSize += delimiterSize(kept)
+	case data.List:
+		outList, isList := out.(data.List)
+		if !isList {
+			r.removed[path] = struct{}{}
+			inSize, outSize = jsonSize(in), jsonSize(out)
+			break
+		}
+		for i, value := range inValue {
+			var outValue data.Value
+			if i < len(outList) {
+				outValue = outList[i]
+			}
+			childIn, childOut := r.add(path+"[]", value, outValue)
+			inSize += childIn
+			if i < len(outList) {
+				outSize += childOut
+			}
+		}
+		inSize += delimiterSize(len(inValue))
+		outSize += delimiterSize(len(outList))
+	default:
+		inSize, outSize = jsonSize(in), jsonSize(out)
+	}
+	waste.Used += outSize
+	waste.Discarded += inSize - outSize
+	return inSize, outSize
+}
+
+// jsonSize returns the length of a value encoded as compact JSON.
+// Only scalar values are marshalled, the sizes of maps and lists are the sum of their contents.
+func jsonSize(value data.Value) int {
+	switch v := value.(type) {
+	case data.Map:
+		var size = delimiterSize(len(v))
+		for key, child := range v {
+			size += keySize(key) + jsonSize(child)
+		}
+		return size
+	case data.List:
+		var size = delimiterSize(len(v))
+		for _, child := range v {
+			size += jsonSize(child)
+		}
+		return size
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(content)
+}
+
+// keySize returns the length of a map key and the following colon, encoded as JSON
+func keySize(key string) int {
+	return jsonSize(data.String(key)) + 1
+}
+
+// delimiterSize returns the length of the brackets and commas surrounding n values
+// in a JSON object or array
+func delimiterSize(n int) int {
+	if n == 0 {
+		return 2
+	}
+	return 2 + n - 1
+}
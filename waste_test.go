@@ -0,0 +1,86 @@
+package soyusage_test
+
+import (
+	"testing"
+
+	"github.com/theothertomelliott/must"
+	"github.com/yext/soy"
+	"github.com/yext/soy/data"
+	"github.com/yext/soyusage"
+)
+
+func TestWasteReport(t *testing.T) {
+	bundle := soy.NewBundle().AddTemplateString("test.soy", `
+	{namespace test}
+	/**
+	* @param loc
+	*/
+	{template .main}
+		{$loc.name}
+		{if $loc.photo}Has photo{/if}
+		{foreach $phone in $loc.phones}
+			{$phone.number}
+		{/foreach}
+	{/template}
+	`)
+	registry, err := bundle.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, err := soyusage.AnalyzeTemplate("test.main", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := soyusage.NewWasteReport()
+	out := report.Add(data.New(map[string]interface{}{
+		"loc": map[string]interface{}{
+			"name":        "a",
+			"description": "long",
+			"photo":       map[string]interface{}{"url": "u"},
+			"phones": []interface{}{
+				map[string]interface{}{"number": "1", "type": "main"},
+			},
+		},
+	}), params)
+	must.BeEqual(t, data.New(map[string]interface{}{
+		"loc": map[string]interface{}{
+			"name":  "a",
+			"photo": "",
+			"phones": []interface{}{
+				map[string]interface{}{"number": "1"},
+			},
+		},
+	}), out)
+	report.Add(data.New(map[string]interface{}{
+		"loc": map[string]interface{}{
+			"name":        "b",
+			"description": "longer",
+		},
+		"unused": true,
+	}), params)
+
+	must.BeEqual(t, 2, report.Samples)
+	must.BeEqual(t, soyusage.PathWaste{Path: "", Used: 77, Discarded: 81}, report.Total())
+	must.BeEqual(t, []soyusage.PathWaste{
+		{Path: "loc.description", Discarded: 14},
+		{Path: "loc.photo", Used: 2, Discarded: 9},
+		{Path: "loc.phones[].type", Discarded: 6},
+		{Path: "unused", Discarded: 4},
+	}, report.Unused(10))
+	must.BeEqual(t, []soyusage.PathWaste{
+		{Path: "loc.description", Discarded: 14},
+	}, report.Unused(1))
+	must.BeEqual(t, []soyusage.PathWaste{
+		{Path: "", Used: 77, Discarded: 81},
+		{Path: "loc", Used: 61, Discarded: 67},
+		{Path: "loc.description", Discarded: 14},
+		{Path: "loc.name", Used: 6},
+		{Path: "loc.phones", Used: 16, Discarded: 14},
+		{Path: "loc.phones[]", Used: 14, Discarded: 14},
+		{Path: "loc.phones[].number", Used: 3},
+		{Path: "loc.phones[].type", Discarded: 6},
+		{Path: "loc.photo", Used: 2, Discarded: 9},
+		{Path: "unused", Discarded: 4},
+	}, report.Paths())
+}